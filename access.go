@@ -0,0 +1,136 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyAccess describes how a key was read, see AccessStats.
+type KeyAccess struct {
+	// Count is the number of times the key was read.
+	Count int64
+
+	// FirstAccess is the time of the first read.
+	FirstAccess time.Time
+}
+
+type keyAccess struct {
+	count int64
+	first time.Time
+}
+
+// accessTracker records the keys read by Get. It's safe for concurrent use,
+// as reads only hold the read lock of the configuration.
+type accessTracker struct {
+	keys sync.Map
+}
+
+func (t *accessTracker) record(key string) {
+	v, ok := t.keys.Load(key)
+	if !ok {
+		v, _ = t.keys.LoadOrStore(key, &keyAccess{first: time.Now()})
+	}
+	atomic.AddInt64(&v.(*keyAccess).count, 1)
+}
+
+// SetAccessTracking enables or disables tracking of the keys read by Get and
+// by the typed getters, including keys read through references. Enabling it
+// again keeps the stats collected so far, disabling it discards them.
+//
+// Tracking is disabled by default, and costs almost nothing while disabled.
+func SetAccessTracking(enabled bool) {
+	DefaultConfig.SetAccessTracking(enabled)
+}
+
+func (c *Configuration) SetAccessTracking(enabled bool) {
+	c.Lock()
+	defer c.Unlock()
+	if !enabled {
+		c.tracker = nil
+	} else if c.tracker == nil {
+		c.tracker = &accessTracker{}
+	}
+}
+
+// AccessStats returns the keys read since access tracking was enabled, along
+// with how many times and when they were first read. It returns nil when
+// tracking is disabled.
+//
+// Checkers that read values, like CheckRegistry, count as accesses too.
+func AccessStats() map[string]KeyAccess {
+	return DefaultConfig.AccessStats()
+}
+
+func (c *Configuration) AccessStats() map[string]KeyAccess {
+	c.RLock()
+	tracker := c.tracker
+	c.RUnlock()
+	if tracker == nil {
+		return nil
+	}
+	stats := make(map[string]KeyAccess)
+	tracker.keys.Range(func(k, v interface{}) bool {
+		access := v.(*keyAccess)
+		stats[k.(string)] = KeyAccess{
+			Count:       atomic.LoadInt64(&access.count),
+			FirstAccess: access.first,
+		}
+		return true
+	})
+	return stats
+}
+
+// UnusedKeys returns the keys defined in the configuration that were never
+// read since access tracking was enabled, sorted by name. Only keys holding
+// values are listed, not sections; reading a section counts as reading every
+// key inside it. Keys with the "_file" suffix are used when the key without
+// the suffix is read.
+//
+// It returns nil when tracking is disabled.
+func UnusedKeys() []string {
+	return DefaultConfig.UnusedKeys()
+}
+
+func (c *Configuration) UnusedKeys() []string {
+	stats := c.AccessStats()
+	if stats == nil {
+		return nil
+	}
+	used := func(key string) bool {
+		parts := strings.Split(strings.TrimSuffix(key, fileKeySuffix), ":")
+		for i := range parts {
+			if _, ok := stats[strings.Join(parts[:i+1], ":")]; ok {
+				return true
+			}
+		}
+		_, ok := stats[key]
+		return ok
+	}
+	unused := []string{}
+	var walk func(m map[interface{}]interface{}, prefix string)
+	walk = func(m map[interface{}]interface{}, prefix string) {
+		for k, v := range m {
+			key := joinKey(prefix, k)
+			if used(key) {
+				continue
+			}
+			if inner, ok := v.(map[interface{}]interface{}); ok && len(inner) > 0 {
+				walk(inner, key)
+				continue
+			}
+			unused = append(unused, key)
+		}
+	}
+	c.RLock()
+	walk(c.data, "")
+	c.RUnlock()
+	sort.Strings(unused)
+	return unused
+}
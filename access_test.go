@@ -0,0 +1,118 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+const accessConfig = `
+database:
+  url: ${config:database:host}:27017
+  host: localhost
+  name: tsuru
+  password_file: /run/secrets/db
+queue:
+  host: redis
+debug: true
+pools: []
+`
+
+func (s *S) TestAccessTrackingDisabled(c *check.C) {
+	var conf Configuration
+	err := conf.ReadConfigBytes([]byte(accessConfig))
+	c.Assert(err, check.IsNil)
+	_, err = conf.Get("debug")
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.AccessStats(), check.IsNil)
+	c.Assert(conf.UnusedKeys(), check.IsNil)
+}
+
+func (s *S) TestAccessStats(c *check.C) {
+	var conf Configuration
+	err := conf.ReadConfigBytes([]byte(accessConfig))
+	c.Assert(err, check.IsNil)
+	conf.SetAccessTracking(true)
+	before := time.Now()
+	_, err = conf.GetBool("debug")
+	c.Assert(err, check.IsNil)
+	_, err = conf.GetBool("debug")
+	c.Assert(err, check.IsNil)
+	_, err = conf.GetString("database:url")
+	c.Assert(err, check.IsNil)
+	_, err = conf.Get("unknown")
+	c.Assert(err, check.NotNil)
+	stats := conf.AccessStats()
+	c.Assert(stats, check.HasLen, 4)
+	c.Assert(stats["debug"].Count, check.Equals, int64(2))
+	c.Assert(stats["debug"].FirstAccess.Before(before), check.Equals, false)
+	c.Assert(stats["database:url"].Count, check.Equals, int64(1))
+	c.Assert(stats["database:host"].Count, check.Equals, int64(1))
+	c.Assert(stats["unknown"].Count, check.Equals, int64(1))
+}
+
+func (s *S) TestUnusedKeys(c *check.C) {
+	var conf Configuration
+	err := conf.ReadConfigBytes([]byte(accessConfig))
+	c.Assert(err, check.IsNil)
+	conf.SetAccessTracking(true)
+	c.Assert(conf.UnusedKeys(), check.DeepEquals, []string{
+		"database:host",
+		"database:name",
+		"database:password_file",
+		"database:url",
+		"debug",
+		"pools",
+		"queue:host",
+	})
+	conf.GetString("database:url")
+	conf.Get("queue")
+	conf.Get("database:password")
+	c.Assert(conf.UnusedKeys(), check.DeepEquals, []string{"database:name", "debug", "pools"})
+}
+
+func (s *S) TestSetAccessTrackingDisableDiscardsStats(c *check.C) {
+	SetAccessTracking(true)
+	defer SetAccessTracking(false)
+	Set("debug", true)
+	GetBool("debug")
+	SetAccessTracking(true)
+	c.Assert(AccessStats(), check.HasLen, 1)
+	c.Assert(UnusedKeys(), check.HasLen, 0)
+	SetAccessTracking(false)
+	SetAccessTracking(true)
+	c.Assert(AccessStats(), check.HasLen, 0)
+	c.Assert(UnusedKeys(), check.DeepEquals, []string{"debug"})
+}
+
+func (s *S) TestAccessTrackingExplainIsNotAnAccess(c *check.C) {
+	var conf Configuration
+	conf.SetAccessTracking(true)
+	conf.Set("debug", true)
+	_, err := conf.Explain("debug")
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.AccessStats(), check.HasLen, 0)
+}
+
+func (s *S) TestAccessTrackingConcurrentReads(c *check.C) {
+	var conf Configuration
+	conf.SetAccessTracking(true)
+	conf.Set("debug", true)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				conf.GetBool("debug")
+			}
+		}()
+	}
+	wg.Wait()
+	c.Assert(conf.AccessStats()["debug"].Count, check.Equals, int64(1000))
+}
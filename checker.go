@@ -7,11 +7,18 @@ package config
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 type Checker func() error
 
-type warningErr struct{ msg string }
+type warningErr struct {
+	msg string
+
+	// keyed holds the warnings behind msg, when they are related to keys,
+	// so RunCheckers can report them one by one.
+	keyed []ValidationError
+}
 
 func (e *warningErr) Error() string {
 	return e.msg
@@ -21,6 +28,31 @@ func NewWarning(msg string) error {
 	return &warningErr{msg: msg}
 }
 
+// keyedWarning returns a warning listing the given problems.
+func keyedWarning(keyed []ValidationError) error {
+	return &warningErr{msg: joinMessages(keyed), keyed: keyed}
+}
+
+// keyedErr is an error made of several problems related to keys, so
+// RunCheckers can report them one by one.
+type keyedErr struct {
+	msg   string
+	keyed []ValidationError
+}
+
+func (e *keyedErr) Error() string {
+	return e.msg
+}
+
+// joinMessages joins the messages of the given problems, without their keys.
+func joinMessages(keyed []ValidationError) string {
+	msgs := make([]string, len(keyed))
+	for i, e := range keyed {
+		msgs[i] = e.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
 // Check a parsed config file and consider warnings as errors.
 func Check(checkers []Checker) error {
 	return CheckWithWarnings(checkers, nil)
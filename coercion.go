@@ -0,0 +1,88 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Coercion defines how typed getters convert values of other types.
+type Coercion int
+
+const (
+	// StrictCoercion is the default policy: GetBool only accepts booleans,
+	// and GetString only accepts strings and integers.
+	StrictCoercion Coercion = iota
+
+	// LenientCoercion makes GetBool accept strings like "true", "yes",
+	// "on" and "1" (and their negative counterparts), as well as the
+	// integers 0 and 1. GetString formats booleans and floats, and GetInt
+	// accepts strings holding whole floats, like "1e3".
+	//
+	// This is useful for values read from environment variables, which are
+	// always strings.
+	LenientCoercion
+)
+
+// SetCoercion defines the coercion policy used by typed getters.
+func SetCoercion(coercion Coercion) {
+	DefaultConfig.SetCoercion(coercion)
+}
+
+func (c *Configuration) SetCoercion(coercion Coercion) {
+	c.Lock()
+	defer c.Unlock()
+	c.coercion = coercion
+}
+
+func (c *Configuration) lenient() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.coercion == LenientCoercion
+}
+
+func lenientString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	}
+	return "", false
+}
+
+func lenientInt(s string) (int, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || f != math.Trunc(f) || f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func lenientBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case int:
+		switch v {
+		case 0:
+			return false, true
+		case 1:
+			return true, true
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "t", "yes", "y", "on", "1":
+			return true, true
+		case "false", "f", "no", "n", "off", "0":
+			return false, true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,194 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"math"
+	"os"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) newCoercionConfig(coercion Coercion) *Configuration {
+	var conf Configuration
+	conf.SetCoercion(coercion)
+	conf.Set("bool", true)
+	conf.Set("float", 1.5)
+	conf.Set("yes", "yes")
+	conf.Set("off", "OFF")
+	conf.Set("one", 1)
+	conf.Set("whole", "1e3")
+	conf.Set("port", "8080")
+	conf.Set("list", []interface{}{"on", "no", 1})
+	return &conf
+}
+
+func (s *S) TestGetStringStrictCoercion(c *check.C) {
+	conf := s.newCoercionConfig(StrictCoercion)
+	_, err := conf.GetString("bool")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"bool", "string|int|int64"})
+	_, err = conf.GetString("float")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"float", "string|int|int64"})
+	value, err := conf.GetString("one")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "1")
+}
+
+func (s *S) TestGetStringLenientCoercion(c *check.C) {
+	conf := s.newCoercionConfig(LenientCoercion)
+	value, err := conf.GetString("bool")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "true")
+	value, err = conf.GetString("float")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "1.5")
+	_, err = conf.GetString("list")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"list", "string|int|int64"})
+}
+
+func (s *S) TestGetBoolStrictCoercion(c *check.C) {
+	conf := s.newCoercionConfig(StrictCoercion)
+	value, err := conf.GetBool("bool")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, true)
+	for _, key := range []string{"yes", "off", "one"} {
+		_, err = conf.GetBool(key)
+		c.Check(err, check.DeepEquals, &InvalidValue{key, "boolean"})
+	}
+}
+
+func (s *S) TestGetBoolLenientCoercion(c *check.C) {
+	conf := s.newCoercionConfig(LenientCoercion)
+	tests := map[string]bool{"bool": true, "yes": true, "off": false, "one": true}
+	for key, expected := range tests {
+		value, err := conf.GetBool(key)
+		c.Check(err, check.IsNil)
+		c.Check(value, check.Equals, expected, check.Commentf("key %q", key))
+	}
+	conf.Set("maybe", "maybe")
+	_, err := conf.GetBool("maybe")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"maybe", "boolean"})
+	conf.Set("two", 2)
+	_, err = conf.GetBool("two")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"two", "boolean"})
+}
+
+func (s *S) TestGetBoolLenientCoercionFromEnv(c *check.C) {
+	os.Setenv("TSURU_DEBUG", "on")
+	defer os.Unsetenv("TSURU_DEBUG")
+	conf := s.newCoercionConfig(LenientCoercion)
+	conf.Set("debug", "$TSURU_DEBUG")
+	value, err := conf.GetBool("debug")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, true)
+	conf.SetCoercion(StrictCoercion)
+	_, err = conf.GetBool("debug")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"debug", "boolean"})
+}
+
+func (s *S) TestGetIntCoercion(c *check.C) {
+	conf := s.newCoercionConfig(StrictCoercion)
+	value, err := conf.GetInt("port")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 8080)
+	_, err = conf.GetInt("whole")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"whole", "int"})
+	conf.SetCoercion(LenientCoercion)
+	value, err = conf.GetInt("whole")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 1000)
+	_, err = conf.GetInt("yes")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"yes", "int"})
+	conf.Set("fraction", "1.5")
+	_, err = conf.GetInt("fraction")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"fraction", "int"})
+	conf.Set("max", "9223372036854775807")
+	value, err = conf.GetInt("max")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, math.MaxInt64)
+	conf.Set("overflow", "9223372036854775808")
+	_, err = conf.GetInt("overflow")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"overflow", "int"})
+	conf.Set("overflow", "9.223372036854775808e18")
+	_, err = conf.GetInt("overflow")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"overflow", "int"})
+}
+
+func (s *S) TestGetUintCoercion(c *check.C) {
+	conf := s.newCoercionConfig(StrictCoercion)
+	_, err := conf.GetUint("whole")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"whole", "uint"})
+	conf.SetCoercion(LenientCoercion)
+	value, err := conf.GetUint("whole")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, uint(1000))
+}
+
+func (s *S) TestGetFloatAndDurationIgnoreCoercion(c *check.C) {
+	for _, coercion := range []Coercion{StrictCoercion, LenientCoercion} {
+		conf := s.newCoercionConfig(coercion)
+		f, err := conf.GetFloat("float")
+		c.Check(err, check.IsNil)
+		c.Check(f, check.Equals, 1.5)
+		_, err = conf.GetFloat("bool")
+		c.Check(err, check.DeepEquals, &InvalidValue{"bool", "float"})
+		d, err := conf.GetDuration("one")
+		c.Check(err, check.IsNil)
+		c.Check(d, check.Equals, time.Duration(1))
+		_, err = conf.GetDuration("yes")
+		c.Check(err, check.DeepEquals, &InvalidValue{"yes", "duration"})
+	}
+}
+
+func (s *S) TestGetListCoercion(c *check.C) {
+	for _, coercion := range []Coercion{StrictCoercion, LenientCoercion} {
+		conf := s.newCoercionConfig(coercion)
+		list, err := conf.GetList("list")
+		c.Check(err, check.IsNil)
+		c.Check(list, check.DeepEquals, []string{"on", "no", "1"})
+	}
+}
+
+func (s *S) TestGetBoolListCoercion(c *check.C) {
+	conf := s.newCoercionConfig(StrictCoercion)
+	_, err := conf.GetBoolList("list")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"list[0]", "boolean"})
+	conf.SetCoercion(LenientCoercion)
+	list, err := conf.GetBoolList("list")
+	c.Assert(err, check.IsNil)
+	c.Assert(list, check.DeepEquals, []bool{true, false, true})
+}
+
+func (s *S) TestGetIntListCoercion(c *check.C) {
+	var conf Configuration
+	conf.Set("list", []interface{}{1, "2e1"})
+	_, err := conf.GetIntList("list")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"list[1]", "int"})
+	conf.SetCoercion(LenientCoercion)
+	list, err := conf.GetIntList("list")
+	c.Assert(err, check.IsNil)
+	c.Assert(list, check.DeepEquals, []int{1, 20})
+}
+
+func (s *S) TestGetStringMapStringCoercion(c *check.C) {
+	var conf Configuration
+	conf.Set("labels", map[interface{}]interface{}{"enabled": true, "ratio": 0.5})
+	_, err := conf.GetStringMapString("labels")
+	c.Assert(err, check.NotNil)
+	conf.SetCoercion(LenientCoercion)
+	labels, err := conf.GetStringMapString("labels")
+	c.Assert(err, check.IsNil)
+	c.Assert(labels, check.DeepEquals, map[string]string{"enabled": "true", "ratio": "0.5"})
+}
+
+func (s *S) TestSetCoercionDefaultConfig(c *check.C) {
+	SetCoercion(LenientCoercion)
+	defer SetCoercion(StrictCoercion)
+	Set("enabled", "yes")
+	value, err := GetBool("enabled")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, true)
+}
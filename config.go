@@ -10,18 +10,21 @@
 package config
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/howeyc/fsnotify"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -37,7 +40,33 @@ func (e ErrKeyNotFound) Error() string {
 }
 
 type Configuration struct {
-	data map[interface{}]interface{}
+	data         map[interface{}]interface{}
+	strictEnv    bool
+	envDisabled  bool
+	envAllow     map[string]bool
+	envPrefixes  []string
+	refAllow     map[string]bool
+	rawKeys      map[string]bool
+	envRefs      map[string][]string
+	undefinedEnv map[string][]string
+	filePaths    []string
+	watcher      *fsnotify.Watcher
+	watchedFile  string
+	filesMu      sync.Mutex
+	files        map[string]string
+	keys         [][]byte
+	trustedKeys  []ed25519.PublicKey
+	reloadErrors chan error
+	sensitive    []string
+	coercion     Coercion
+	defaults     map[interface{}]interface{}
+	tracker      *accessTracker
+	aliases      map[string][]string
+	deprecated   map[string]deprecation
+	migrations   []migration
+	verbatim     bool
+	reloadSchema *jsonschema.Schema
+	enums        map[string]Enum
 	sync.RWMutex
 }
 
@@ -49,6 +78,8 @@ func (c *Configuration) Store(data map[interface{}]interface{}) {
 
 func (c *Configuration) store(data map[interface{}]interface{}) {
 	c.data = data
+	c.scanEnv(data)
+	c.scanFiles(data)
 }
 
 func (c *Configuration) Data() map[interface{}]interface{} {
@@ -63,18 +94,28 @@ var DefaultConfig Configuration
 // configuration object.
 //
 // If the given slice is not a valid yaml file, ReadConfigBytes returns a
-// non-nil error.
+// non-nil error. Registered migrations run before the configuration is
+// stored, see RegisterMigration.
 func ReadConfigBytes(data []byte) error {
 	return DefaultConfig.ReadConfigBytes(data)
 }
 
 func (c *Configuration) ReadConfigBytes(data []byte) error {
+	newConfig, err := parseYAML(data)
+	if err != nil {
+		return err
+	}
+	if newConfig, _, err = c.migrate(newConfig); err != nil {
+		return err
+	}
+	c.Store(newConfig)
+	return nil
+}
+
+func parseYAML(data []byte) (map[interface{}]interface{}, error) {
 	var newConfig map[interface{}]interface{}
 	err := yaml.Unmarshal(data, &newConfig)
-	if err == nil {
-		c.Store(newConfig)
-	}
-	return err
+	return newConfig, err
 }
 
 // ReadConfigFile reads the content of a file and calls ReadConfigBytes to
@@ -91,6 +132,9 @@ func (c *Configuration) ReadConfigFile(filePath string) error {
 	if err != nil {
 		return err
 	}
+	if err = c.verifySignature(filePath, data); err != nil {
+		return err
+	}
 	return c.ReadConfigBytes(data)
 }
 
@@ -98,6 +142,10 @@ func (c *Configuration) ReadConfigFile(filePath string) error {
 // file. Whenever the file change, and its contents are valid YAML, the
 // configuration gets updated. With this function, daemons that use this
 // package may reload configuration without restarting.
+//
+// When the file can not be reloaded, the configuration is kept unchanged and
+// the error is reported through the channel returned by ReloadErrors. Reloaded
+// files may be validated against a JSON Schema too, see SetReloadSchema.
 func ReadAndWatchConfigFile(filePath string) error {
 	return DefaultConfig.ReadAndWatchConfigFile(filePath)
 }
@@ -113,14 +161,38 @@ func (c *Configuration) ReadAndWatchConfigFile(filePath string) error {
 	}
 	err = w.Watch(filePath)
 	if err != nil {
+		w.Close()
 		return err
 	}
+	sigPath := signaturePath(filePath)
+	c.Lock()
+	if len(c.trustedKeys) > 0 {
+		if err = w.Watch(sigPath); err != nil {
+			c.Unlock()
+			w.Close()
+			return err
+		}
+	}
+	c.watcher = w
+	c.watchedFile = filePath
+	c.watchFiles()
+	c.Unlock()
 	go func() {
 		for {
 			select {
 			case e := <-w.Event:
-				if e.IsModify() {
-					c.ReadConfigFile(filePath)
+				changed := e.IsModify()
+				if e.IsDelete() || e.IsRename() {
+					// Renaming a file over a watched one, which is how
+					// secrets are usually rotated, drops the watch.
+					changed = w.Watch(e.Name) == nil
+				}
+				if e.Name != filePath && e.Name != sigPath {
+					c.forgetFile(e.Name)
+				} else if changed {
+					if err := c.reloadConfigFile(filePath); err != nil {
+						c.reportError(err)
+					}
 				}
 			case <-w.Error: // just ignore errors
 			}
@@ -129,7 +201,32 @@ func (c *Configuration) ReadAndWatchConfigFile(filePath string) error {
 	return nil
 }
 
-// Bytes serialize the configuration in YAML format.
+// ReloadErrors returns a channel that receives the errors found while
+// reloading configuration files watched with ReadAndWatchConfigFile. Errors
+// are discarded when nobody is receiving them and the channel buffer is full.
+func ReloadErrors() <-chan error {
+	return DefaultConfig.ReloadErrors()
+}
+
+func (c *Configuration) ReloadErrors() <-chan error {
+	c.Lock()
+	defer c.Unlock()
+	if c.reloadErrors == nil {
+		c.reloadErrors = make(chan error, 16)
+	}
+	return c.reloadErrors
+}
+
+func (c *Configuration) reportError(err error) {
+	c.ReloadErrors()
+	select {
+	case c.reloadErrors <- err:
+	default:
+	}
+}
+
+// Bytes serialize the configuration in YAML format. Use RedactedBytes to hide
+// the values of sensitive keys.
 func Bytes() ([]byte, error) {
 	return DefaultConfig.Bytes()
 }
@@ -152,6 +249,12 @@ func (c *Configuration) WriteConfigFile(filePath string, perm os.FileMode) error
 	if err != nil {
 		return err
 	}
+	return writeNewFile(filePath, b, perm)
+}
+
+// writeNewFile writes b to the file in the given path, failing if the file
+// already exists.
+func writeNewFile(filePath string, b []byte, perm os.FileMode) error {
 	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_EXCL, perm)
 	if err != nil {
 		return err
@@ -167,6 +270,34 @@ func (c *Configuration) WriteConfigFile(filePath string, perm os.FileMode) error
 	return nil
 }
 
+// replaceConfigFile writes the given configuration tree to a temporary file
+// and renames it over the file in the given path, so readers never see a
+// partial file. The rename drops the watch on the file, when it's being
+// watched by ReadAndWatchConfigFile, so it's watched again.
+func (c *Configuration) replaceConfigFile(filePath string, data map[interface{}]interface{}, perm os.FileMode) error {
+	b, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp")
+	os.Remove(tmp)
+	if err := writeNewFile(tmp, b, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	c.RLock()
+	w, watched := c.watcher, c.watchedFile
+	c.RUnlock()
+	if w != nil && watched == filePath {
+		return w.Watch(filePath)
+	}
+	return nil
+}
+
 // Get returns the value for the given key, or an error if the key is undefined.
 //
 // The key is composed of all the key names separated by :, in case of nested
@@ -180,6 +311,13 @@ func (c *Configuration) WriteConfigFile(filePath string, perm os.FileMode) error
 // The key "databases:mysql:host" would return "localhost", while the key
 // "port" would return an error.
 //
+// When the key is not defined, Get reads the keys it aliases (see Alias) and
+// then returns its default value, if any (see SetDefault).
+//
+// Keys explicitly set to null (for example "host: ~") are defined, so Get
+// returns a nil value without an error for them. Typed getters, like
+// GetString and GetInt, return ErrNullValue instead, see IsNull.
+//
 // Get will expand the value with environment values, ex.:
 //
 //   mongo: $MONGOURI
@@ -187,30 +325,122 @@ func (c *Configuration) WriteConfigFile(filePath string, perm os.FileMode) error
 // If there is an environment variable MONGOURI=localhost/test, the key "mongo"
 // would return "localhost/test". If the variable value is a json object/list, this
 // object will also be expanded.
+//
+// References may also define a default value, or mark the variable as
+// required, using the shell syntax:
+//
+//   port: ${PORT:-8080}
+//   mongo: ${MONGOURI:?must be set}
+//   price: $$10
+//
+// When a required variable is not set, Get returns an ErrRequiredEnv error. A
+// literal $ is written as $$.
+//
+// Values may also reference other keys in the same configuration:
+//
+//   database:
+//     host: db.example.com
+//     url: mongodb://${config:database:host}/tsuru
+//
+// A value made of a single reference returns the referenced value as is,
+// including objects and lists. References that form a cycle make Get return
+// an ErrReferenceCycle error; use Explain to see the chain of references
+// behind a value.
+//
+// Secrets may be kept in files, either referenced in values or using a key
+// with the "_file" suffix:
+//
+//   database:
+//     password_file: /run/secrets/db_password
+//     url: mongodb://admin:${file:/run/secrets/db_password}@localhost/tsuru
+//
+// Here, the key "database:password" returns the contents of the file, without
+// trailing line breaks. References like ${file:...} must be enabled with
+// AllowReferences("file"), otherwise they're kept verbatim. Contents are
+// cached until the configuration is reloaded or, when using
+// ReadAndWatchConfigFile, until the file changes.
+//
+// Values in the format ENC[...], including items of lists, are decrypted using
+// the keys loaded by LoadKeyFile, see Encrypt and EncryptConfigFile.
 func Get(key string) (interface{}, error) {
 	return DefaultConfig.Get(key)
 }
 
 func (c *Configuration) Get(key string) (interface{}, error) {
-	keys := strings.Split(key, ":")
 	c.RLock()
 	defer c.RUnlock()
-	conf, ok := c.data[keys[0]]
+	return c.get(key, &resolution{})
+}
+
+// get works like Get, but the caller must hold the lock. The resolution keeps
+// track of the references followed while expanding values.
+func (c *Configuration) get(key string, r *resolution) (interface{}, error) {
+	if err := r.enter(key); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+	if c.tracker != nil && !r.tracing {
+		c.tracker.record(key)
+	}
+	value, err := c.find(c.data, key, r)
+	if _, ok := err.(ErrKeyNotFound); ok && !c.verbatim && !strings.HasSuffix(key, fileKeySuffix) {
+		if path, pathErr := c.find(c.data, key+fileKeySuffix, r); pathErr == nil {
+			return c.readFileKey(key+fileKeySuffix, path)
+		}
+	}
+	if _, ok := err.(ErrKeyNotFound); ok && c.aliases != nil {
+		for _, old := range c.aliasesOf(key) {
+			aliasValue, aliasErr := c.get(old, r)
+			if _, ok := aliasErr.(ErrKeyNotFound); !ok {
+				return aliasValue, aliasErr
+			}
+		}
+	}
+	if c.defaults == nil {
+		return value, err
+	}
+	if _, ok := err.(ErrKeyNotFound); ok {
+		return c.find(c.defaults, key, r)
+	}
+	if m, ok := value.(map[interface{}]interface{}); ok && err == nil {
+		if def, defErr := c.find(c.defaults, key, r); defErr == nil {
+			if defMap, ok := def.(map[interface{}]interface{}); ok {
+				return mergeMaps(defMap, m), nil
+			}
+		}
+	}
+	return value, err
+}
+
+// find looks up the value for the given key in the given configuration tree,
+// and expands it.
+func (c *Configuration) find(data map[interface{}]interface{}, key string, r *resolution) (interface{}, error) {
+	keys := strings.Split(key, ":")
+	conf, ok := data[keys[0]]
 	if !ok {
 		return nil, ErrKeyNotFound{Key: key}
 	}
-	for _, k := range keys[1:] {
+	for i, k := range keys[1:] {
 		switch configEntry := conf.(type) {
 		case map[interface{}]interface{}:
 			if conf, ok = configEntry[k]; !ok {
 				return nil, ErrKeyNotFound{Key: key}
 			}
 		case string:
-			value, err := expandEnv(configEntry)
+			parent := strings.Join(keys[:i+1], ":")
+			if c.isRaw(parent) {
+				return nil, ErrMismatchConf
+			}
+			r.step(parent, configEntry)
+			value, err := c.expandEnv(configEntry, r)
 			if err != nil {
+				return nil, withKey(err, key)
+			}
+			m, ok := value.(map[interface{}]interface{})
+			if !ok {
 				return nil, ErrMismatchConf
 			}
-			if conf, ok = value.(map[interface{}]interface{})[k]; !ok {
+			if conf, ok = m[k]; !ok {
 				return nil, ErrKeyNotFound{Key: key}
 			}
 		default:
@@ -220,17 +450,70 @@ func (c *Configuration) Get(key string) (interface{}, error) {
 	if v, ok := conf.(func() interface{}); ok {
 		conf = v()
 	}
-	if v, ok := conf.(string); ok {
-		value, _ := expandEnv(v)
+	r.step(key, conf)
+	if v, ok := conf.(string); ok && !c.isRaw(key) {
+		if isEncrypted(v) {
+			plain, err := c.decrypt(v)
+			if err != nil {
+				return nil, &ErrDecrypt{Key: key, Err: err}
+			}
+			return plain, nil
+		}
+		value, err := c.expandEnv(v, r)
+		if err != nil {
+			return nil, withKey(err, key)
+		}
 		return value, nil
 	}
 	return conf, nil
 }
 
-// expandEnv expands an environment variable and unmarshalls
-// an json object or slice if it's found.
-func expandEnv(s string) (interface{}, error) {
-	raw := os.ExpandEnv(s)
+// raw returns the value for the given key as stored in the configuration
+// tree, without any expansion. The caller must hold the lock.
+func (c *Configuration) raw(key string) (interface{}, bool) {
+	return lookup(c.data, key)
+}
+
+// lookup returns the value for the given key in the configuration tree,
+// without expanding it.
+func lookup(data map[interface{}]interface{}, key string) (interface{}, bool) {
+	var value interface{} = data
+	for _, k := range strings.Split(key, ":") {
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// expandEnv expands environment variables and references in the given string
+// and unmarshalls an json object or slice if it's found. It only returns an
+// error if the string can not be interpolated, values that are not valid json
+// are returned as plain strings.
+//
+// A string made of a single reference to another key expands to the value of
+// that key, whatever its type.
+func (c *Configuration) expandEnv(s string, r *resolution) (interface{}, error) {
+	if ref, ok := singleReference(s); ok && !c.envDisabled {
+		return c.resolveReference(ref, r)
+	}
+	raw, err := c.interpolate(s, r)
+	if err != nil {
+		return nil, err
+	}
+	value, err := decodeJSON(raw)
+	if err != nil {
+		return raw, nil
+	}
+	return value, nil
+}
+
+// decodeJSON unmarshalls a json object or slice from the given string.
+func decodeJSON(raw string) (interface{}, error) {
 	var jsonMap map[string]interface{}
 	if len(raw) == 0 || (raw[0] != '{' && raw[0] != '[') {
 		return raw, errNotJSON
@@ -247,6 +530,25 @@ func expandEnv(s string) (interface{}, error) {
 	return toInfMap(jsonMap), nil
 }
 
+// withKey fills the key in errors that are generated without knowing which
+// configuration entry is being read.
+func withKey(err error, key string) error {
+	switch e := err.(type) {
+	case ErrRequiredEnv:
+		e.Key = key
+		return e
+	case ErrUndefinedEnv:
+		e.Key = key
+		return e
+	case *ErrReference:
+		if e.Key == "" {
+			e.Key = key
+		}
+		return e
+	}
+	return err
+}
+
 // toInfMap takes an map[string]interface{} and recursively converts it
 // to an map[interface{}]interface{}.
 func toInfMap(sMap map[string]interface{}) map[interface{}]interface{} {
@@ -265,97 +567,136 @@ func toInfMap(sMap map[string]interface{}) map[interface{}]interface{} {
 }
 
 // GetString works like Get, but does an string type assertion before returning
-// the value.
+// the value. With LenientCoercion, booleans and floats are formatted as
+// strings too, see SetCoercion.
 //
-// It returns error if the key is undefined or if it is not a string.
+// It returns error if the key is undefined, ErrNullValue if it is set to null,
+// or InvalidValue if it is not a string.
 func GetString(key string) (string, error) {
 	return DefaultConfig.GetString(key)
 }
 
 func (c *Configuration) GetString(key string) (string, error) {
-	value, err := c.Get(key)
+	value, err := c.value(key)
 	if err != nil {
 		return "", err
 	}
+	if v, ok := toString(value, c.lenient()); ok {
+		return v, nil
+	}
+	return "", &InvalidValue{key, "string|int|int64"}
+}
+
+func toString(value interface{}, lenient bool) (string, bool) {
 	switch v := value.(type) {
 	case int:
-		return strconv.Itoa(v), nil
+		return strconv.Itoa(v), true
 	case int64:
-		return strconv.FormatInt(v, 10), nil
+		return strconv.FormatInt(v, 10), true
 	case string:
-		return v, nil
+		return v, true
+	case Secret:
+		return string(v), true
 	}
-	return "", &InvalidValue{key, "string|int|int64"}
+	if lenient {
+		return lenientString(value)
+	}
+	return "", false
 }
 
 // GetInt works like Get, but does an int type assertion and attempts string
 // conversion before returning the value.
 //
-// It returns error if the key is undefined or if it is not a int.
+// It returns error if the key is undefined, ErrNullValue if it is set to null,
+// or InvalidValue if it is not a int.
 func GetInt(key string) (int, error) {
 	return DefaultConfig.GetInt(key)
 }
 
 func (c *Configuration) GetInt(key string) (int, error) {
-	value, err := c.Get(key)
+	value, err := c.value(key)
 	if err != nil {
 		return 0, err
 	}
-	if v, ok := value.(int); ok {
+	if v, ok := toInt(value, c.lenient()); ok {
 		return v, nil
+	}
+	return 0, &InvalidValue{key, "int"}
+}
+
+func toInt(value interface{}, lenient bool) (int, bool) {
+	if v, ok := value.(int); ok {
+		return v, true
 	} else if v, ok := value.(string); ok {
 		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
-			return int(i), nil
+			return int(i), true
+		}
+		if lenient {
+			return lenientInt(v)
 		}
-	} else if v, err := c.GetFloat(key); err == nil {
+	} else if v, ok := toFloat(value); ok {
 		if float64(int(v)) == v {
-			return int(v), nil
+			return int(v), true
 		}
 	}
-	return 0, &InvalidValue{key, "int"}
+	return 0, false
 }
 
 // GetFloat works like Get, but does a float type assertion and attempts string
 // conversion before returning the value.
 //
-// It returns error if the key is undefined or if it is not a float.
+// It returns error if the key is undefined, ErrNullValue if it is set to null,
+// or InvalidValue if it is not a float.
 func GetFloat(key string) (float64, error) {
 	return DefaultConfig.GetFloat(key)
 }
 
 func (c *Configuration) GetFloat(key string) (float64, error) {
-	value, err := c.Get(key)
+	value, err := c.value(key)
 	if err != nil {
 		return 0, err
 	}
+	if v, ok := toFloat(value); ok {
+		return v, nil
+	}
+	return 0, &InvalidValue{key, "float"}
+}
+
+func toFloat(value interface{}) (float64, bool) {
 	switch v := value.(type) {
 	case float64:
-		return v, nil
+		return v, true
 	case float32:
-		return float64(v), nil
+		return float64(v), true
 	case int:
-		return float64(v), nil
+		return float64(v), true
 	case string:
 		if floatVal, err := strconv.ParseFloat(v, 64); err == nil {
-			return floatVal, nil
+			return floatVal, true
 		}
 	}
-	return 0, &InvalidValue{key, "float"}
+	return 0, false
 }
 
 // GetUint parses and returns an unsigned integer from the config file.
+//
+// It returns ErrNullValue if the key is set to null.
 func GetUint(key string) (uint, error) {
 	return DefaultConfig.GetUint(key)
 }
 
 func (c *Configuration) GetUint(key string) (uint, error) {
-	if v, err := c.GetInt(key); err == nil {
+	v, err := c.GetInt(key)
+	if err == nil {
 		if v < 0 {
 			return 0, &InvalidValue{key, "uint"}
 		}
 		return uint(v), nil
 	}
-	return 0, &InvalidValue{key, "uint"}
+	if _, ok := err.(*InvalidValue); ok {
+		return 0, &InvalidValue{key, "uint"}
+	}
+	return 0, err
 }
 
 // GetDuration parses and returns a duration from the config file. It may be an
@@ -368,47 +709,75 @@ func (c *Configuration) GetUint(key string) (uint, error) {
 //  - 100e6 (one hundred milliseconds)
 //  - 1 (one nanosecond)
 //  - 1000000000 (one billion nanoseconds, or one second)
+//
+// Besides the units accepted by time.ParseDuration, durations may use "d" for
+// days (24 hours) and "w" for weeks (7 days), or the ISO-8601 format, without
+// years and months:
+//
+//  - 1w2d12h (nine and a half days)
+//  - PT30S (thirty seconds)
+//  - P1DT12H (one day and a half)
+//
+// It returns ErrNullValue if the key is set to null.
 func GetDuration(key string) (time.Duration, error) {
 	return DefaultConfig.GetDuration(key)
 }
 
 func (c *Configuration) GetDuration(key string) (time.Duration, error) {
-	value, err := c.Get(key)
+	value, err := c.value(key)
 	if err != nil {
 		return 0, err
 	}
+	if v, ok := toDuration(value); ok {
+		return v, nil
+	}
+	return 0, &InvalidValue{key, "duration"}
+}
+
+func toDuration(value interface{}) (time.Duration, bool) {
 	switch v := value.(type) {
 	case int:
-		return time.Duration(v), nil
+		return time.Duration(v), true
 	case float64:
-		return time.Duration(v), nil
+		return time.Duration(v), true
 	case string:
-		if duration, err := time.ParseDuration(v); err == nil {
-			return duration, nil
-		}
-		if number, err := strconv.ParseFloat(v, 64); err == nil {
-			return time.Duration(number), nil
+		if duration, err := parseDuration(v); err == nil {
+			return duration, true
 		}
 	}
-	return 0, &InvalidValue{key, "duration"}
+	return 0, false
 }
 
-// GetBool does a type assertion before returning the requested value
+// GetBool does a type assertion before returning the requested value. With
+// LenientCoercion, common spellings of booleans are accepted as well, see
+// SetCoercion.
+//
+// It returns ErrNullValue if the key is set to null.
 func GetBool(key string) (bool, error) {
 	return DefaultConfig.GetBool(key)
 }
 
 func (c *Configuration) GetBool(key string) (bool, error) {
-	value, err := c.Get(key)
+	value, err := c.value(key)
 	if err != nil {
 		return false, err
 	}
-	if v, ok := value.(bool); ok {
+	if v, ok := toBool(value, c.lenient()); ok {
 		return v, nil
 	}
 	return false, &InvalidValue{key, "boolean"}
 }
 
+func toBool(value interface{}, lenient bool) (bool, bool) {
+	if v, ok := value.(bool); ok {
+		return v, true
+	}
+	if lenient {
+		return lenientBool(value)
+	}
+	return false, false
+}
+
 // GetList works like Get, but returns a slice of strings instead. It must be
 // written down in the config as YAML lists.
 //
@@ -422,12 +791,14 @@ func (c *Configuration) GetBool(key string) (bool, error) {
 //
 // If GetList find an item that is not a string (for example 5.08734792), it
 // will convert the item.
+//
+// It returns ErrNullValue if the key is set to null.
 func GetList(key string) ([]string, error) {
 	return DefaultConfig.GetList(key)
 }
 
 func (c *Configuration) GetList(key string) ([]string, error) {
-	value, err := c.Get(key)
+	value, err := c.value(key)
 	if err != nil {
 		return nil, err
 	}
@@ -443,7 +814,11 @@ func (c *Configuration) GetList(key string) ([]string, error) {
 			case float64:
 				result[i] = strconv.FormatFloat(v, 'f', -1, 64)
 			case string:
-				result[i] = os.ExpandEnv(v)
+				if result[i], err = c.expandString(key, v); err != nil {
+					return nil, withKey(err, key)
+				}
+			case Secret:
+				result[i] = string(v)
 			default:
 				result[i] = fmt.Sprintf("%v", item)
 			}
@@ -497,6 +872,15 @@ func Set(key string, value interface{}) {
 }
 
 func (c *Configuration) Set(key string, value interface{}) {
+	last := keyTree(key, value)
+	c.Lock()
+	defer c.Unlock()
+	c.store(mergeMaps(c.data, last))
+}
+
+// keyTree builds a configuration tree holding only the given value, nested
+// under the parts of the key.
+func keyTree(key string, value interface{}) map[interface{}]interface{} {
 	parts := strings.Split(key, ":")
 	last := map[interface{}]interface{}{
 		parts[len(parts)-1]: value,
@@ -506,13 +890,12 @@ func (c *Configuration) Set(key string, value interface{}) {
 			parts[i]: last,
 		}
 	}
-	c.Lock()
-	defer c.Unlock()
-	c.store(mergeMaps(c.data, last))
+	return last
 }
 
 // Unset removes a key from the configuration map. It returns an error if the
-// key is not defined.
+// key is not defined. Defaults are not affected, so unsetting a key makes Get
+// return its default again, see SetDefault.
 //
 // Calling this function does not remove a key from a configuration file, only
 // from the in-memory configuration object.
@@ -430,7 +430,17 @@ func (s *S) TestGetUint(c *check.C) {
 	_, err = GetUint("auth:salt")
 	c.Assert(err, check.NotNil)
 	_, err = GetUint("Unknown")
-	c.Assert(err, check.NotNil)
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "Unknown"})
+}
+
+func (s *S) TestGetUintKeepsExpansionErrors(c *check.C) {
+	var conf Configuration
+	conf.Set("a", "${NOPE_X:?must be set}")
+	_, err := conf.GetUint("a")
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Key: "a", Name: "NOPE_X", Message: "must be set"})
+	conf.Set("a", "-1")
+	_, err = conf.GetUint("a")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"a", "uint"})
 }
 
 func (s *S) TestGetUintExpandVarsJsonObject(c *check.C) {
@@ -0,0 +1,227 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+	keySize         = 32
+)
+
+var errNoKeys = errors.New("no encryption keys loaded")
+
+// ErrDecrypt is returned by Get when an encrypted value can not be decrypted
+// with any of the loaded keys.
+type ErrDecrypt struct {
+	Key string
+	Err error
+}
+
+func (e *ErrDecrypt) Error() string {
+	return fmt.Sprintf("failed to decrypt the value for the key %q: %s", e.Key, e.Err)
+}
+
+func (e *ErrDecrypt) Unwrap() error {
+	return e.Err
+}
+
+// LoadKeyFile loads the encryption keys from the given file. Encrypted values,
+// in the format ENC[...], are decrypted by Get using these keys.
+//
+// The file contains one base64 encoded 32 bytes key per line, blank lines and
+// lines starting with # are ignored. Values are encrypted with the first key
+// in the file and decrypted with any of them, which allows rotating keys.
+// Values are encrypted using AES-256 in GCM mode.
+func LoadKeyFile(filePath string) error {
+	return DefaultConfig.LoadKeyFile(filePath)
+}
+
+func (c *Configuration) LoadKeyFile(filePath string) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var keys [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return fmt.Errorf("invalid key in %s: %s", filePath, err)
+		}
+		if len(key) != keySize {
+			return fmt.Errorf("invalid key in %s: keys must have %d bytes", filePath, keySize)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no keys found in %s", filePath)
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.keys = keys
+	return nil
+}
+
+// GenerateKeyFile creates a key file, in the format expected by LoadKeyFile,
+// with a new random key. It fails if the file already exists.
+func GenerateKeyFile(filePath string) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, base64.StdEncoding.EncodeToString(key))
+	return err
+}
+
+// Encrypt encrypts the given value with the first loaded key, returning it in
+// the format ENC[...], which can be stored in the configuration file.
+func Encrypt(value string) (string, error) {
+	return DefaultConfig.Encrypt(value)
+}
+
+func (c *Configuration) Encrypt(value string) (string, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.encrypt(value)
+}
+
+func (c *Configuration) encrypt(value string) (string, error) {
+	if len(c.keys) == 0 {
+		return "", errNoKeys
+	}
+	gcm, err := newGCM(c.keys[0])
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+// decrypt decrypts a value in the format ENC[...]. The caller must hold the
+// lock.
+func (c *Configuration) decrypt(value string) (string, error) {
+	if len(c.keys) == 0 {
+		return "", errNoKeys
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encryptedPrefix) : len(value)-len(encryptedSuffix)])
+	if err != nil {
+		return "", err
+	}
+	for _, key := range c.keys {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		if len(sealed) < gcm.NonceSize() {
+			return "", errors.New("encrypted value is too short")
+		}
+		nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		if plain, err := gcm.Open(nil, nonce, ciphertext, nil); err == nil {
+			return string(plain), nil
+		}
+	}
+	return "", errors.New("no key matches the encrypted value")
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// EncryptConfigFile encrypts the values for the given keys in the
+// configuration file in the given path, and rewrites it. Only the contents of
+// the file are written, values set with Set or merged from other files are
+// left out. Keys that are already encrypted are left untouched.
+//
+// Keys of the in-memory configuration that still hold the value read from the
+// file are updated with the encrypted value.
+func EncryptConfigFile(filePath string, perm os.FileMode, keys ...string) error {
+	return DefaultConfig.EncryptConfigFile(filePath, perm, keys...)
+}
+
+func (c *Configuration) EncryptConfigFile(filePath string, perm os.FileMode, keys ...string) error {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	data, err := parseYAML(b)
+	if err != nil {
+		return err
+	}
+	encrypted := make(map[string]string, len(keys))
+	plains := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		value, ok := lookup(data, key)
+		if !ok {
+			return ErrKeyNotFound{Key: key}
+		}
+		var plain string
+		switch v := value.(type) {
+		case string:
+			if isEncrypted(v) {
+				continue
+			}
+			plain = v
+		case map[interface{}]interface{}, []interface{}, nil:
+			return &InvalidValue{key, "scalar"}
+		default:
+			plain = fmt.Sprint(v)
+		}
+		if encrypted[key], err = c.Encrypt(plain); err != nil {
+			return err
+		}
+		plains[key] = value
+	}
+	for key, value := range encrypted {
+		data = mergeMaps(data, keyTree(key, value))
+	}
+	if err = c.replaceConfigFile(filePath, data, perm); err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	current := c.data
+	for key, value := range encrypted {
+		if v, ok := c.raw(key); ok && v == plains[key] {
+			current = mergeMaps(current, keyTree(key, value))
+		}
+	}
+	c.store(current)
+	return nil
+}
@@ -0,0 +1,268 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func newKeyFile(c *check.C) string {
+	path := filepath.Join(c.MkDir(), "config.key")
+	err := GenerateKeyFile(path)
+	c.Assert(err, check.IsNil)
+	return path
+}
+
+func (s *S) TestEncryptAndGet(c *check.C) {
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	encrypted, err := conf.Encrypt("s3cr3t$HOME")
+	c.Assert(err, check.IsNil)
+	c.Assert(encrypted, check.Matches, `ENC\[.+\]`)
+	c.Assert(strings.Contains(encrypted, "s3cr3t"), check.Equals, false)
+	conf.Set("database:password", encrypted)
+	value, err := conf.GetString("database:password")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "s3cr3t$HOME")
+	data, err := conf.Bytes()
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(string(data), encrypted), check.Equals, true)
+}
+
+func (s *S) TestGetEncryptedListsAndSections(c *check.C) {
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	encrypted, err := conf.Encrypt("s3cr3t")
+	c.Assert(err, check.IsNil)
+	port, err := conf.Encrypt("27017")
+	c.Assert(err, check.IsNil)
+	conf.Set("database:list", []interface{}{encrypted, "plain"})
+	conf.Set("database:ports", []interface{}{port})
+	conf.Set("labels:password", encrypted)
+	conf.Set("pools", []interface{}{
+		map[interface{}]interface{}{"name": "default", "password": encrypted},
+	})
+	list, err := conf.GetList("database:list")
+	c.Assert(err, check.IsNil)
+	c.Assert(list, check.DeepEquals, []string{"s3cr3t", "plain"})
+	ports, err := conf.GetIntList("database:ports")
+	c.Assert(err, check.IsNil)
+	c.Assert(ports, check.DeepEquals, []int{27017})
+	labels, err := conf.GetStringMapString("labels")
+	c.Assert(err, check.IsNil)
+	c.Assert(labels, check.DeepEquals, map[string]string{"password": "s3cr3t"})
+	var pools []struct {
+		Name     string `yaml:"name"`
+		Password string `yaml:"password"`
+	}
+	err = conf.Unmarshal("pools", &pools)
+	c.Assert(err, check.IsNil)
+	c.Assert(pools[0].Password, check.Equals, "s3cr3t")
+	var db struct {
+		List []string `yaml:"list"`
+	}
+	err = conf.Unmarshal("database", &db)
+	c.Assert(err, check.IsNil)
+	c.Assert(db.List, check.DeepEquals, []string{"s3cr3t", "plain"})
+}
+
+func (s *S) TestGetEncryptedListWithoutKeys(c *check.C) {
+	var conf Configuration
+	conf.Set("database:list", []interface{}{"ENC[c29tZXRoaW5n]"})
+	_, err := conf.GetList("database:list")
+	c.Assert(err, check.ErrorMatches, `failed to decrypt the value for the key "database:list": no encryption keys loaded`)
+}
+
+func (s *S) TestEncryptWithoutKeys(c *check.C) {
+	var conf Configuration
+	_, err := conf.Encrypt("s3cr3t")
+	c.Assert(err, check.Equals, errNoKeys)
+}
+
+func (s *S) TestGetEncryptedWithoutKeys(c *check.C) {
+	var conf Configuration
+	conf.Set("database:password", "ENC[c29tZXRoaW5n]")
+	_, err := conf.Get("database:password")
+	c.Assert(err, check.FitsTypeOf, &ErrDecrypt{})
+	c.Assert(err.Error(), check.Equals, `failed to decrypt the value for the key "database:password": no encryption keys loaded`)
+}
+
+func (s *S) TestGetEncryptedWithWrongKey(c *check.C) {
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	encrypted, err := conf.Encrypt("s3cr3t")
+	c.Assert(err, check.IsNil)
+	err = conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	conf.Set("database:password", encrypted)
+	_, err = conf.Get("database:password")
+	c.Assert(err, check.ErrorMatches, `failed to decrypt the value for the key "database:password": no key matches the encrypted value`)
+}
+
+func (s *S) TestLoadKeyFileRotation(c *check.C) {
+	oldKey, err := ioutil.ReadFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	newKey, err := ioutil.ReadFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	dir := c.MkDir()
+	oldPath := writeSecret(c, dir, "old.key", string(oldKey))
+	rotatedPath := writeSecret(c, dir, "rotated.key", "# current key\n"+string(newKey)+"\n# previous key\n"+string(oldKey))
+	var conf Configuration
+	err = conf.LoadKeyFile(oldPath)
+	c.Assert(err, check.IsNil)
+	encrypted, err := conf.Encrypt("s3cr3t")
+	c.Assert(err, check.IsNil)
+	err = conf.LoadKeyFile(rotatedPath)
+	c.Assert(err, check.IsNil)
+	conf.Set("password", encrypted)
+	value, err := conf.GetString("password")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "s3cr3t")
+}
+
+func (s *S) TestLoadKeyFileInvalid(c *check.C) {
+	dir := c.MkDir()
+	var conf Configuration
+	err := conf.LoadKeyFile(writeSecret(c, dir, "empty.key", "# nothing here\n"))
+	c.Assert(err, check.ErrorMatches, "no keys found in .*")
+	err = conf.LoadKeyFile(writeSecret(c, dir, "short.key", "c2hvcnQ=\n"))
+	c.Assert(err, check.ErrorMatches, "invalid key in .*: keys must have 32 bytes")
+	err = conf.LoadKeyFile(writeSecret(c, dir, "invalid.key", "not base64!\n"))
+	c.Assert(err, check.ErrorMatches, "invalid key in .*")
+	err = conf.LoadKeyFile("/some/unknown/file")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestGenerateKeyFileDoesNotOverwrite(c *check.C) {
+	path := newKeyFile(c)
+	err := GenerateKeyFile(path)
+	c.Assert(err, check.NotNil)
+}
+
+func (s *S) TestEncryptConfigFile(c *check.C) {
+	dir := c.MkDir()
+	configPath := writeSecret(c, dir, "tsuru.conf", "database:\n  host: localhost\n  password: s3cr3t\n  port: 27017\n")
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	err = conf.ReadConfigFile(configPath)
+	c.Assert(err, check.IsNil)
+	err = conf.EncryptConfigFile(configPath, 0600, "database:password", "database:port")
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadFile(configPath)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(string(data), "s3cr3t"), check.Equals, false)
+	c.Assert(strings.Contains(string(data), "host: localhost"), check.Equals, true)
+	var other Configuration
+	other.keys = conf.keys
+	err = other.ReadConfigFile(configPath)
+	c.Assert(err, check.IsNil)
+	password, err := other.GetString("database:password")
+	c.Assert(err, check.IsNil)
+	c.Assert(password, check.Equals, "s3cr3t")
+	port, err := other.GetInt("database:port")
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 27017)
+	encrypted, err := other.Get("database:password")
+	c.Assert(err, check.IsNil)
+	err = other.EncryptConfigFile(configPath, 0600, "database:password")
+	c.Assert(err, check.IsNil)
+	again, err := other.Get("database:password")
+	c.Assert(err, check.IsNil)
+	c.Assert(again, check.Equals, encrypted)
+}
+
+func (s *S) TestEncryptConfigFileFailureKeepsConfiguration(c *check.C) {
+	dir := c.MkDir()
+	content := "database:\n  password: s3cr3t\n  hosts: [a, b]\n"
+	configPath := writeSecret(c, dir, "tsuru.conf", content)
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	err = conf.ReadConfigFile(configPath)
+	c.Assert(err, check.IsNil)
+	err = conf.EncryptConfigFile(configPath, 0600, "database:password", "database:hosts")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"database:hosts", "scalar"})
+	value, ok := conf.raw("database:password")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(value, check.Equals, "s3cr3t")
+	data, err := ioutil.ReadFile(configPath)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, content)
+}
+
+func (s *S) TestEncryptConfigFileKeepsWatching(c *check.C) {
+	dir := c.MkDir()
+	configPath := writeSecret(c, dir, "tsuru.conf", "a: 1\npassword: s3cr3t\n")
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	err = conf.ReadAndWatchConfigFile(configPath)
+	c.Assert(err, check.IsNil)
+	err = conf.EncryptConfigFile(configPath, 0600, "password")
+	c.Assert(err, check.IsNil)
+	time.Sleep(1e8)
+	data, err := ioutil.ReadFile(configPath)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(configPath, bytes.Replace(data, []byte("a: 1"), []byte("a: 2"), 1), 0600)
+	c.Assert(err, check.IsNil)
+	time.Sleep(1e8)
+	value, err := conf.GetInt("a")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 2)
+	password, err := conf.GetString("password")
+	c.Assert(err, check.IsNil)
+	c.Assert(password, check.Equals, "s3cr3t")
+}
+
+func (s *S) TestEncryptConfigFileUnknownKey(c *check.C) {
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	conf.Set("database:password", "s3cr3t")
+	configPath := writeSecret(c, c.MkDir(), "tsuru.conf", "database:\n  host: localhost\n")
+	err = conf.EncryptConfigFile(configPath, 0600, "database:password")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "database:password"})
+}
+
+func (s *S) TestEncryptConfigFileWritesOnlyFileContents(c *check.C) {
+	dir := c.MkDir()
+	configPath := writeSecret(c, dir, "tsuru.conf", "password: s3cr3t\ntoken: abc\n")
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	err = conf.ReadConfigFile(configPath)
+	c.Assert(err, check.IsNil)
+	conf.Set("token", Secret("xyz"))
+	conf.Set("runtime", "value")
+	err = conf.EncryptConfigFile(configPath, 0600, "password", "token")
+	c.Assert(err, check.IsNil)
+	data, err := ioutil.ReadFile(configPath)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(string(data), "***"), check.Equals, false)
+	c.Assert(strings.Contains(string(data), "runtime"), check.Equals, false)
+	var other Configuration
+	other.keys = conf.keys
+	err = other.ReadConfigFile(configPath)
+	c.Assert(err, check.IsNil)
+	token, err := other.GetString("token")
+	c.Assert(err, check.IsNil)
+	c.Assert(token, check.Equals, "abc")
+	value, ok := conf.raw("password")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(isEncrypted(value.(string)), check.Equals, true)
+	value, ok = conf.raw("token")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(value, check.Equals, Secret("xyz"))
+}
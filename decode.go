@@ -0,0 +1,209 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Unmarshal decodes the section under the given key into v, which must be a
+// pointer, using the same rules of yaml.Unmarshal. An empty key decodes the
+// whole configuration. Values are expanded like Get does before decoding:
+// expanded strings decoded into string fields are kept as is, while other
+// fields read them like YAML would, so "port: $PORT" may be decoded into an
+// int.
+//
+// For example, given the following configuration:
+//
+//   database:
+//     host: $DBHOST
+//     port: 27017
+//
+// The section "database" may be decoded into a struct:
+//
+//   var db struct {
+//       Host string `yaml:"host"`
+//       Port int    `yaml:"port"`
+//   }
+//   err := config.Unmarshal("database", &db)
+//
+// It returns ErrNullValue if the key is set to null.
+func Unmarshal(key string, v interface{}) error {
+	return DefaultConfig.Unmarshal(key, v)
+}
+
+func (c *Configuration) Unmarshal(key string, v interface{}) error {
+	var value interface{}
+	if key == "" {
+		value = c.Data()
+	} else {
+		var err error
+		if value, err = c.value(key); err != nil {
+			return err
+		}
+	}
+	value, err := c.expandValue(key, value, false, reflect.TypeOf(v))
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(data, v); err != nil {
+		if key == "" {
+			return fmt.Errorf("failed to decode the configuration: %s", err)
+		}
+		return fmt.Errorf("failed to decode the value for the key %q: %s", key, err)
+	}
+	return nil
+}
+
+// anyType is the type of values decoded into interface{}.
+var anyType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// expandTree returns a copy of the value read from the given key, with every
+// nested value expanded like Get does.
+func (c *Configuration) expandTree(key string, value interface{}) (interface{}, error) {
+	return c.expandValue(key, value, false, nil)
+}
+
+// expandValue expands the given value, read from the given key. Values inside
+// lists can't be read with Get, so listed is true for them and their strings
+// are expanded in the context of the list key.
+//
+// target is the type the value is going to be decoded into, if any. Expanded
+// strings decoded into anything but strings are given the type they would
+// have if they were written in the configuration file, see resolveScalar.
+func (c *Configuration) expandValue(key string, value interface{}, listed bool, target reflect.Type) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[interface{}]interface{}, len(v))
+		for k, item := range v {
+			itemKey := joinKey(key, k)
+			itemType := fieldType(target, fmt.Sprint(k))
+			if raw, ok := item.(string); ok {
+				var err error
+				if listed {
+					item, err = c.expandString(key, raw)
+					err = withKey(err, key)
+				} else {
+					item, err = c.Get(itemKey)
+				}
+				if err != nil {
+					return nil, err
+				}
+				item = resolveScalar(raw, item, itemType)
+			}
+			expanded, err := c.expandValue(itemKey, item, listed, itemType)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = expanded
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		itemType := elemType(target)
+		for i, item := range v {
+			if raw, ok := item.(string); ok {
+				var err error
+				if item, err = c.expandString(key, raw); err != nil {
+					return nil, withKey(err, key)
+				}
+				item = resolveScalar(raw, item, itemType)
+			}
+			expanded, err := c.expandValue(key, item, true, itemType)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = expanded
+		}
+		return result, nil
+	case Secret:
+		return string(v), nil
+	}
+	return value, nil
+}
+
+// resolveScalar returns the expanded value with the type it would have if it
+// was written in the configuration file, so "$PORT" may be decoded into an
+// int. Values that were not changed by the expansion, or that are decoded
+// into strings, are kept as is: YAML would read 0123 as an octal number and
+// yes as a boolean.
+func resolveScalar(raw string, expanded interface{}, target reflect.Type) interface{} {
+	s, ok := expanded.(string)
+	if !ok || s == raw || target == nil || indirect(target).Kind() == reflect.String {
+		return expanded
+	}
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(s), &value); err != nil {
+		return expanded
+	}
+	switch value.(type) {
+	case int, int64, uint64, float64, bool:
+		return value
+	}
+	return expanded
+}
+
+// fieldType returns the type the value for the given name, inside a value
+// decoded into t, is decoded into. It returns nil when it's unknown.
+func fieldType(t reflect.Type, name string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t = indirect(t); t.Kind() {
+	case reflect.Interface:
+		return t
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		var inlined []reflect.Type
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldName, inline, ok := yamlField(field)
+			if !ok {
+				continue
+			}
+			if inline {
+				inlined = append(inlined, field.Type)
+			} else if fieldName == name {
+				return field.Type
+			}
+		}
+		for _, ft := range inlined {
+			if ft = fieldType(ft, name); ft != nil {
+				return ft
+			}
+		}
+	}
+	return nil
+}
+
+// elemType returns the type the items of a list decoded into t are decoded
+// into. It returns nil when it's unknown.
+func elemType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t = indirect(t); t.Kind() {
+	case reflect.Interface:
+		return t
+	case reflect.Slice, reflect.Array:
+		return t.Elem()
+	}
+	return nil
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
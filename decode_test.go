@@ -0,0 +1,133 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+type databaseConfig struct {
+	Host     string        `yaml:"host"`
+	Port     int           `yaml:"port"`
+	Password Secret        `yaml:"password"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Replicas []string      `yaml:"replicas"`
+}
+
+func (s *S) TestUnmarshal(c *check.C) {
+	os.Setenv("DBHOST", "6.6.6.6")
+	defer os.Unsetenv("DBHOST")
+	err := ReadConfigBytes([]byte(`
+database:
+  host: $DBHOST
+  port: ${DBPORT:-27017}
+  password: s3cr3t
+  timeout: 5000000000
+  replicas: [$DBHOST, other]
+`))
+	c.Assert(err, check.IsNil)
+	var db databaseConfig
+	err = Unmarshal("database", &db)
+	c.Assert(err, check.IsNil)
+	c.Assert(db, check.DeepEquals, databaseConfig{
+		Host:     "6.6.6.6",
+		Port:     27017,
+		Password: Secret("s3cr3t"),
+		Timeout:  5 * time.Second,
+		Replicas: []string{"6.6.6.6", "other"},
+	})
+}
+
+func (s *S) TestUnmarshalMapsInsideLists(c *check.C) {
+	os.Setenv("DBHOST", "6.6.6.6")
+	defer os.Unsetenv("DBHOST")
+	err := ReadConfigBytes([]byte(`
+databases:
+  - host: $DBHOST
+    port: ${DBPORT:-27017}
+  - host: other
+`))
+	c.Assert(err, check.IsNil)
+	var dbs []databaseConfig
+	err = Unmarshal("databases", &dbs)
+	c.Assert(err, check.IsNil)
+	c.Assert(dbs, check.DeepEquals, []databaseConfig{
+		{Host: "6.6.6.6", Port: 27017},
+		{Host: "other"},
+	})
+}
+
+func (s *S) TestUnmarshalKeepsExpandedStrings(c *check.C) {
+	os.Setenv("ZZPASS", "0123")
+	defer os.Unsetenv("ZZPASS")
+	os.Setenv("ZZHOST", "yes")
+	defer os.Unsetenv("ZZHOST")
+	os.Setenv("ZZPORT", "0123")
+	defer os.Unsetenv("ZZPORT")
+	err := ReadConfigBytes([]byte(`
+database:
+  host: $ZZHOST
+  port: $ZZPORT
+  password: $ZZPASS
+  replicas: [$ZZPASS]
+`))
+	c.Assert(err, check.IsNil)
+	var db databaseConfig
+	err = Unmarshal("database", &db)
+	c.Assert(err, check.IsNil)
+	c.Assert(db.Host, check.Equals, "yes")
+	c.Assert(db.Port, check.Equals, 83)
+	c.Assert(db.Password, check.Equals, Secret("0123"))
+	c.Assert(db.Replicas, check.DeepEquals, []string{"0123"})
+	other, err := GetAs[struct {
+		Password string `yaml:"password"`
+	}](nil, "database")
+	c.Assert(err, check.IsNil)
+	c.Assert(other.Password, check.Equals, "0123")
+	password, err := GetString("database:password")
+	c.Assert(err, check.IsNil)
+	c.Assert(password, check.Equals, other.Password)
+}
+
+func (s *S) TestUnmarshalWholeConfig(c *check.C) {
+	err := ReadConfigFile("testdata/config.yml")
+	c.Assert(err, check.IsNil)
+	var conf struct {
+		Database databaseConfig `yaml:"database"`
+		Names    []string       `yaml:"names"`
+	}
+	err = Unmarshal("", &conf)
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.Database.Host, check.Equals, "127.0.0.1")
+	c.Assert(conf.Database.Port, check.Equals, 8080)
+	c.Assert(conf.Names, check.DeepEquals, []string{"Mary", "John", "Anthony", "Gopher"})
+}
+
+func (s *S) TestUnmarshalJsonExpansion(c *check.C) {
+	os.Setenv("DATABASE", `{"host": "6.6.6.6", "port": 27017}`)
+	defer os.Unsetenv("DATABASE")
+	Set("database", "$DATABASE")
+	var db databaseConfig
+	err := Unmarshal("database", &db)
+	c.Assert(err, check.IsNil)
+	c.Assert(db.Host, check.Equals, "6.6.6.6")
+	c.Assert(db.Port, check.Equals, 27017)
+}
+
+func (s *S) TestUnmarshalErrors(c *check.C) {
+	Set("database:port", "not a number")
+	var db databaseConfig
+	err := Unmarshal("database", &db)
+	c.Assert(err, check.ErrorMatches, `(?s)failed to decode the value for the key "database": .*`)
+	err = Unmarshal("unknown", &db)
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "unknown"})
+	os.Unsetenv("DBHOST")
+	Set("database:host", "${DBHOST:?}")
+	err = Unmarshal("database", &db)
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Key: "database:host", Name: "DBHOST"})
+}
@@ -0,0 +1,58 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SetDefault defines the default value for a key. The key has the same format
+// that it has in Get.
+//
+// Defaults are kept apart from the configuration data, as a layer beneath it:
+// Get returns the default when the key is not defined, and sections read by
+// Get include the defaults of their keys. Keys explicitly set to null keep
+// the null value. Reloading the configuration file, Set and Unset don't
+// affect defaults.
+func SetDefault(key string, value interface{}) {
+	DefaultConfig.SetDefault(key, value)
+}
+
+func (c *Configuration) SetDefault(key string, value interface{}) {
+	tree := keyTree(key, value)
+	c.Lock()
+	defer c.Unlock()
+	c.defaults = mergeMaps(c.defaults, tree)
+}
+
+// RegisterDefaults calls SetDefault for every key in the given map, for
+// example:
+//
+//   config.RegisterDefaults(map[string]interface{}{
+//       "database:url":  "127.0.0.1:27017",
+//       "database:name": "tsuru",
+//   })
+func RegisterDefaults(defaults map[string]interface{}) {
+	DefaultConfig.RegisterDefaults(defaults)
+}
+
+func (c *Configuration) RegisterDefaults(defaults map[string]interface{}) {
+	for key, value := range defaults {
+		c.SetDefault(key, value)
+	}
+}
+
+// BytesWithDefaults works like Bytes, but includes the defaults of keys that
+// are not defined in the configuration.
+func BytesWithDefaults() ([]byte, error) {
+	return DefaultConfig.BytesWithDefaults()
+}
+
+func (c *Configuration) BytesWithDefaults() ([]byte, error) {
+	c.RLock()
+	data := mergeMaps(c.defaults, c.data)
+	c.RUnlock()
+	return yaml.Marshal(data)
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestSetDefault(c *check.C) {
+	var conf Configuration
+	conf.SetDefault("database:name", "tsuru")
+	conf.SetDefault("database:port", 27017)
+	err := conf.ReadConfigBytes([]byte("database:\n  name: tsuru-dev\n"))
+	c.Assert(err, check.IsNil)
+	name, err := conf.GetString("database:name")
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "tsuru-dev")
+	port, err := conf.GetInt("database:port")
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 27017)
+	_, err = conf.Get("database:host")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "database:host"})
+}
+
+func (s *S) TestSetDefaultWithoutData(c *check.C) {
+	var conf Configuration
+	conf.SetDefault("timeout", "10s")
+	timeout, err := conf.GetDuration("timeout")
+	c.Assert(err, check.IsNil)
+	c.Assert(timeout, check.Equals, 10*time.Second)
+}
+
+func (s *S) TestSetDefaultMergesSections(c *check.C) {
+	var conf Configuration
+	conf.SetDefault("database:name", "tsuru")
+	conf.SetDefault("database:port", 27017)
+	conf.Set("database:name", "tsuru-dev")
+	section, err := conf.GetStringMap("database")
+	c.Assert(err, check.IsNil)
+	c.Assert(section, check.DeepEquals, map[string]interface{}{"name": "tsuru-dev", "port": 27017})
+	var db databaseConfig
+	err = conf.Unmarshal("database", &db)
+	c.Assert(err, check.IsNil)
+	c.Assert(db.Port, check.Equals, 27017)
+}
+
+func (s *S) TestSetDefaultExpandVars(c *check.C) {
+	os.Setenv("DBHOST", "db.example.com")
+	defer os.Unsetenv("DBHOST")
+	var conf Configuration
+	conf.SetDefault("database:host", "${DBHOST:-localhost}")
+	host, err := conf.GetString("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "db.example.com")
+}
+
+func (s *S) TestSetDefaultNullValue(c *check.C) {
+	var conf Configuration
+	conf.SetDefault("database:host", "localhost")
+	conf.Set("database:host", nil)
+	c.Assert(conf.IsNull("database:host"), check.Equals, true)
+}
+
+func (s *S) TestUnsetRevealsDefault(c *check.C) {
+	var conf Configuration
+	conf.SetDefault("database:host", "localhost")
+	conf.Set("database:host", "db.example.com")
+	err := conf.Unset("database:host")
+	c.Assert(err, check.IsNil)
+	host, err := conf.GetString("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "localhost")
+	err = conf.Unset("database:host")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "database:host"})
+}
+
+func (s *S) TestDefaultsSurviveReload(c *check.C) {
+	var conf Configuration
+	conf.SetDefault("debug", true)
+	err := conf.ReadConfigBytes([]byte("debug: false\n"))
+	c.Assert(err, check.IsNil)
+	err = conf.ReadConfigBytes([]byte("other: 1\n"))
+	c.Assert(err, check.IsNil)
+	debug, err := conf.GetBool("debug")
+	c.Assert(err, check.IsNil)
+	c.Assert(debug, check.Equals, true)
+}
+
+func (s *S) TestRegisterDefaults(c *check.C) {
+	RegisterDefaults(map[string]interface{}{
+		"database:url":  "127.0.0.1:27017",
+		"database:name": "tsuru",
+	})
+	defer func() { DefaultConfig.defaults = nil }()
+	url, err := GetString("database:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(url, check.Equals, "127.0.0.1:27017")
+	name, err := GetString("database:name")
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "tsuru")
+}
+
+func (s *S) TestBytesWithDefaults(c *check.C) {
+	var conf Configuration
+	conf.SetDefault("database:name", "tsuru")
+	conf.SetDefault("debug", false)
+	conf.Set("database:name", "tsuru-dev")
+	b, err := conf.Bytes()
+	c.Assert(err, check.IsNil)
+	c.Assert(string(b), check.Equals, "database:\n  name: tsuru-dev\n")
+	b, err = conf.BytesWithDefaults()
+	c.Assert(err, check.IsNil)
+	c.Assert(string(b), check.Equals, "database:\n  name: tsuru-dev\ndebug: false\n")
+}
@@ -0,0 +1,147 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type deprecation struct {
+	replacement string
+	message     string
+}
+
+func (d deprecation) warning(key string) string {
+	msg := fmt.Sprintf("key %q is deprecated", key)
+	if d.replacement != "" {
+		msg += fmt.Sprintf(", use %q instead", d.replacement)
+	}
+	if d.message != "" {
+		msg += ": " + d.message
+	}
+	return msg
+}
+
+// Alias makes newKey read the value of oldKey when newKey is not defined,
+// which keeps old configuration files working after a key is renamed. Keys
+// inside aliased sections are aliased too: after Alias("docker", "cluster"),
+// Get("cluster:host") reads "docker:host".
+//
+// The old key is also deprecated in favor of the new one, see Deprecate.
+//
+// Alias panics if the new key would end up aliasing itself, for example after
+// Alias("a", "b") and Alias("b", "a"), or Alias("a", "a:b").
+func Alias(oldKey, newKey string) {
+	DefaultConfig.Alias(oldKey, newKey)
+}
+
+func (c *Configuration) Alias(oldKey, newKey string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.aliasReaches(oldKey, newKey, map[string]bool{}) {
+		panic(fmt.Sprintf("config: alias from %q to %q makes a cycle", oldKey, newKey))
+	}
+	if c.aliases == nil {
+		c.aliases = make(map[string][]string)
+	}
+	c.aliases[newKey] = append(c.aliases[newKey], oldKey)
+	if _, ok := c.deprecated[oldKey]; !ok {
+		c.deprecate(oldKey, newKey, "")
+	}
+}
+
+// Deprecate marks the given key as deprecated, optionally pointing to its
+// replacement and explaining the deprecation. CheckDeprecated warns about
+// deprecated keys present in the configuration.
+func Deprecate(key, replacement, message string) {
+	DefaultConfig.Deprecate(key, replacement, message)
+}
+
+func (c *Configuration) Deprecate(key, replacement, message string) {
+	c.Lock()
+	defer c.Unlock()
+	c.deprecate(key, replacement, message)
+}
+
+func (c *Configuration) deprecate(key, replacement, message string) {
+	if c.deprecated == nil {
+		c.deprecated = make(map[string]deprecation)
+	}
+	c.deprecated[key] = deprecation{replacement: replacement, message: message}
+}
+
+// aliasReaches reports whether reading the given key may end up reading the
+// target key, or one of its sections, through aliases. The caller must hold
+// the lock.
+func (c *Configuration) aliasReaches(key, target string, visited map[string]bool) bool {
+	if overlaps(key, target) {
+		return true
+	}
+	for newKey, oldKeys := range c.aliases {
+		if !overlaps(key, newKey) {
+			continue
+		}
+		for _, old := range oldKeys {
+			if !visited[old] {
+				visited[old] = true
+				if c.aliasReaches(old, target, visited) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// overlaps reports whether the given keys are the same, or one of them is a
+// section of the other.
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+":") || strings.HasPrefix(b, a+":")
+}
+
+// aliasesOf returns the keys aliased by the given key, or by its sections,
+// starting by the closest ones. The caller must hold the lock.
+func (c *Configuration) aliasesOf(key string) []string {
+	var keys []string
+	parts := strings.Split(key, ":")
+	for i := len(parts); i > 0; i-- {
+		rest := strings.Join(parts[i:], ":")
+		for _, old := range c.aliases[strings.Join(parts[:i], ":")] {
+			if rest != "" {
+				old += ":" + rest
+			}
+			keys = append(keys, old)
+		}
+	}
+	return keys
+}
+
+// CheckDeprecated is a Checker that emits a warning (see NewWarning) listing
+// the deprecated keys defined in the configuration, for example:
+//
+//   key "docker:registry" is deprecated, use "registry" instead
+func CheckDeprecated() error {
+	return DefaultConfig.CheckDeprecated()
+}
+
+func (c *Configuration) CheckDeprecated() error {
+	c.RLock()
+	defer c.RUnlock()
+	var keyed []ValidationError
+	for key, d := range c.deprecated {
+		if _, ok := c.raw(key); ok {
+			keyed = append(keyed, ValidationError{Key: key, Message: d.warning(key)})
+		} else if _, ok := c.raw(key + fileKeySuffix); ok {
+			keyed = append(keyed, ValidationError{Key: key + fileKeySuffix, Message: d.warning(key + fileKeySuffix)})
+		}
+	}
+	if len(keyed) == 0 {
+		return nil
+	}
+	sort.Slice(keyed, func(i, j int) bool { return keyed[i].Message < keyed[j].Message })
+	return keyedWarning(keyed)
+}
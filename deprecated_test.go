@@ -0,0 +1,119 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestAlias(c *check.C) {
+	var conf Configuration
+	conf.Alias("docker:registry", "registry:url")
+	conf.Set("docker:registry", "registry.example.com")
+	value, err := conf.GetString("registry:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "registry.example.com")
+	conf.Set("registry:url", "new.example.com")
+	value, err = conf.GetString("registry:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "new.example.com")
+}
+
+func (s *S) TestAliasSection(c *check.C) {
+	var conf Configuration
+	conf.Alias("docker", "cluster")
+	conf.Set("docker:host", "tcp://localhost:2375")
+	value, err := conf.GetString("cluster:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "tcp://localhost:2375")
+	section, err := conf.GetStringMap("cluster")
+	c.Assert(err, check.IsNil)
+	c.Assert(section, check.DeepEquals, map[string]interface{}{"host": "tcp://localhost:2375"})
+	_, err = conf.Get("cluster:port")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "cluster:port"})
+}
+
+func (s *S) TestAliasWinsOverDefault(c *check.C) {
+	var conf Configuration
+	conf.Alias("docker:registry", "registry:url")
+	conf.SetDefault("registry:url", "localhost:5000")
+	value, err := conf.GetString("registry:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "localhost:5000")
+	conf.Set("docker:registry", "registry.example.com")
+	value, err = conf.GetString("registry:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "registry.example.com")
+}
+
+func (s *S) TestAliasNullValue(c *check.C) {
+	var conf Configuration
+	conf.Alias("old", "new")
+	conf.Set("old", nil)
+	c.Assert(conf.IsNull("new"), check.Equals, true)
+}
+
+func (s *S) TestAliasCycle(c *check.C) {
+	var conf Configuration
+	conf.Alias("a", "b")
+	c.Assert(func() { conf.Alias("b", "a") }, check.PanicMatches, `config: alias from "b" to "a" makes a cycle`)
+	c.Assert(func() { conf.Alias("a", "a:b") }, check.PanicMatches, `config: alias from "a" to "a:b" makes a cycle`)
+	conf.Alias("c:x", "a")
+	c.Assert(func() { conf.Alias("b:y", "c") }, check.PanicMatches, `config: alias from "b:y" to "c" makes a cycle`)
+	_, err := conf.Get("a")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "a"})
+	_, err = conf.Get("b")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "b"})
+}
+
+func (s *S) TestAliasDefaultConfig(c *check.C) {
+	Alias("old", "new")
+	defer func() {
+		DefaultConfig.aliases = nil
+		DefaultConfig.deprecated = nil
+	}()
+	Set("old", 10)
+	value, err := GetInt("new")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 10)
+}
+
+func (s *S) TestCheckDeprecated(c *check.C) {
+	var conf Configuration
+	conf.Alias("docker:registry", "registry:url")
+	conf.Deprecate("docker:segregate", "", "pools are always segregated now")
+	conf.Deprecate("auth:salt", "auth:hash-cost", "")
+	conf.Deprecate("queue", "", "")
+	c.Assert(conf.CheckDeprecated(), check.IsNil)
+	conf.Set("docker:registry", "registry.example.com")
+	conf.Set("docker:segregate", true)
+	conf.Set("auth:salt_file", "/run/secrets/salt")
+	err := conf.CheckDeprecated()
+	c.Assert(err, check.FitsTypeOf, &warningErr{})
+	c.Assert(err, check.ErrorMatches, `key "auth:salt_file" is deprecated, use "auth:hash-cost" instead; `+
+		`key "docker:registry" is deprecated, use "registry:url" instead; `+
+		`key "docker:segregate" is deprecated: pools are always segregated now`)
+}
+
+func (s *S) TestCheckDeprecatedExplicitMessageWins(c *check.C) {
+	var conf Configuration
+	conf.Deprecate("docker:registry", "registry:url", "see the upgrade notes")
+	conf.Alias("docker:registry", "registry:url")
+	conf.Set("docker:registry", "registry.example.com")
+	err := conf.CheckDeprecated()
+	c.Assert(err, check.ErrorMatches, `key "docker:registry" is deprecated, use "registry:url" instead: see the upgrade notes`)
+}
+
+func (s *S) TestCheckDeprecatedWithWarnings(c *check.C) {
+	Deprecate("debug", "log:level", "")
+	defer func() { DefaultConfig.deprecated = nil }()
+	Set("debug", true)
+	var buf bytes.Buffer
+	err := CheckWithWarnings([]Checker{CheckDeprecated}, &buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "WARNING: key \"debug\" is deprecated, use \"log:level\" instead\n")
+}
@@ -0,0 +1,121 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Enum describes the set of values accepted by a key.
+type Enum struct {
+	// Values lists the accepted values.
+	Values []string
+
+	// IgnoreCase makes values match regardless of case. The value returned
+	// by GetEnum is always the one listed in Values.
+	IgnoreCase bool
+}
+
+// Match returns the allowed value matching the given value, if any.
+func (e Enum) Match(value string) (string, bool) {
+	for _, v := range e.Values {
+		if v == value || (e.IgnoreCase && strings.EqualFold(v, value)) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (e Enum) kind() string {
+	return fmt.Sprintf("valid value (one of: %s)", strings.Join(e.Values, ", "))
+}
+
+// RegisterEnum defines the values accepted by the given key. Registered enums
+// are used by GetEnum, when it's called without allowed values, and by
+// CheckEnums. For example:
+//
+//   config.RegisterEnum("provisioner", config.Enum{Values: []string{"docker", "kubernetes"}})
+func RegisterEnum(key string, enum Enum) {
+	DefaultConfig.RegisterEnum(key, enum)
+}
+
+func (c *Configuration) RegisterEnum(key string, enum Enum) {
+	c.Lock()
+	defer c.Unlock()
+	if c.enums == nil {
+		c.enums = make(map[string]Enum)
+	}
+	c.enums[key] = enum
+}
+
+// GetEnum returns the value for the given key, ensuring it's one of the
+// allowed values. When no allowed values are given, the enum registered for
+// the key with RegisterEnum is used.
+//
+// The error returned for invalid values lists the allowed values. It returns
+// ErrNullValue if the key is set to null.
+func GetEnum(key string, allowed ...string) (string, error) {
+	return DefaultConfig.GetEnum(key, allowed...)
+}
+
+func (c *Configuration) GetEnum(key string, allowed ...string) (string, error) {
+	enum := Enum{Values: allowed}
+	if len(allowed) == 0 {
+		var ok bool
+		if enum, ok = c.enum(key); !ok {
+			return "", fmt.Errorf("no enum registered for the key %q", key)
+		}
+	}
+	return c.getEnum(key, enum)
+}
+
+func (c *Configuration) getEnum(key string, enum Enum) (string, error) {
+	value, err := c.stringValue(key, enum.kind())
+	if err != nil {
+		return "", err
+	}
+	if v, ok := enum.Match(value); ok {
+		return v, nil
+	}
+	return "", &InvalidValue{key, enum.kind()}
+}
+
+func (c *Configuration) enum(key string) (Enum, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	enum, ok := c.enums[key]
+	return enum, ok
+}
+
+// CheckEnums is a Checker that fails if any key registered with RegisterEnum
+// holds a value that is not allowed. Keys that are not defined, or are set to
+// null, are ignored.
+func CheckEnums() error {
+	return DefaultConfig.CheckEnums()
+}
+
+func (c *Configuration) CheckEnums() error {
+	c.RLock()
+	keys := make([]string, 0, len(c.enums))
+	for key := range c.enums {
+		keys = append(keys, key)
+	}
+	c.RUnlock()
+	sort.Strings(keys)
+	var keyed []ValidationError
+	for _, key := range keys {
+		enum, _ := c.enum(key)
+		_, err := c.getEnum(key, enum)
+		if err != nil && !isMissing(err) {
+			keyed = append(keyed, ValidationError{Key: key, Message: err.Error()})
+		}
+	}
+	if len(keyed) == 0 {
+		return nil
+	}
+	return &keyedErr{msg: joinMessages(keyed), keyed: keyed}
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestGetEnum(c *check.C) {
+	Set("provisioner", "kubernetes")
+	Set("port", 8080)
+	value, err := GetEnum("provisioner", "docker", "kubernetes")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "kubernetes")
+	_, err = GetEnum("provisioner", "docker", "swarm")
+	c.Assert(err, check.FitsTypeOf, &InvalidValue{})
+	c.Assert(err, check.ErrorMatches, `value for the key "provisioner" is not a valid value \(one of: docker, swarm\)`)
+	_, err = GetEnum("port", "docker")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"port", "valid value (one of: docker)"})
+	_, err = GetEnum("unknown", "docker")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "unknown"})
+}
+
+func (s *S) TestGetEnumIsCaseSensitive(c *check.C) {
+	Set("provisioner", "Docker")
+	_, err := GetEnum("provisioner", "docker")
+	c.Assert(err, check.FitsTypeOf, &InvalidValue{})
+}
+
+func (s *S) TestGetEnumExpandVars(c *check.C) {
+	os.Setenv("TSURU_PROVISIONER", "docker")
+	defer os.Unsetenv("TSURU_PROVISIONER")
+	Set("provisioner", "$TSURU_PROVISIONER")
+	value, err := GetEnum("provisioner", "docker", "kubernetes")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "docker")
+}
+
+func (s *S) TestRegisterEnum(c *check.C) {
+	var conf Configuration
+	conf.RegisterEnum("provisioner", Enum{Values: []string{"docker", "kubernetes"}, IgnoreCase: true})
+	conf.Set("provisioner", "Kubernetes")
+	value, err := conf.GetEnum("provisioner")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "kubernetes")
+	conf.Set("provisioner", "swarm")
+	_, err = conf.GetEnum("provisioner")
+	c.Assert(err, check.ErrorMatches, `value for the key "provisioner" is not a valid value \(one of: docker, kubernetes\)`)
+	_, err = conf.GetEnum("other")
+	c.Assert(err, check.ErrorMatches, `no enum registered for the key "other"`)
+}
+
+func (s *S) TestEnumMatch(c *check.C) {
+	enum := Enum{Values: []string{"docker", "kubernetes"}}
+	_, ok := enum.Match("DOCKER")
+	c.Assert(ok, check.Equals, false)
+	enum.IgnoreCase = true
+	value, ok := enum.Match("DOCKER")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(value, check.Equals, "docker")
+}
+
+func (s *S) TestCheckEnums(c *check.C) {
+	var conf Configuration
+	conf.RegisterEnum("provisioner", Enum{Values: []string{"docker", "kubernetes"}})
+	conf.RegisterEnum("log:level", Enum{Values: []string{"debug", "info"}})
+	conf.RegisterEnum("queue", Enum{Values: []string{"redis"}})
+	conf.Set("provisioner", "docker")
+	c.Assert(conf.CheckEnums(), check.IsNil)
+	conf.Set("provisioner", "swarm")
+	conf.Set("log:level", "verbose")
+	err := conf.CheckEnums()
+	c.Assert(err, check.ErrorMatches, `value for the key "log:level" is not a valid value \(one of: debug, info\); `+
+		`value for the key "provisioner" is not a valid value \(one of: docker, kubernetes\)`)
+}
+
+func (s *S) TestCheckEnumsChecker(c *check.C) {
+	RegisterEnum("provisioner", Enum{Values: []string{"docker"}})
+	defer func() { DefaultConfig.enums = nil }()
+	Set("provisioner", "swarm")
+	err := Check([]Checker{CheckEnums})
+	c.Assert(err, check.ErrorMatches, `value for the key "provisioner" is not a valid value \(one of: docker\)`)
+}
@@ -0,0 +1,605 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ErrRequiredEnv is returned by Get when a value references a required
+// environment variable, using the ${NAME:?message} or ${NAME?message} syntax,
+// and the variable is not defined.
+type ErrRequiredEnv struct {
+	Key     string
+	Name    string
+	Message string
+}
+
+func (e ErrRequiredEnv) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = "not set"
+	}
+	if e.Key == "" {
+		return fmt.Sprintf("environment variable %q is required: %s", e.Name, msg)
+	}
+	return fmt.Sprintf("value for the key %q requires the environment variable %q: %s", e.Key, e.Name, msg)
+}
+
+// ErrUndefinedEnv is returned by Get, in strict mode, when a value references
+// an environment variable that is not defined and has no default value.
+type ErrUndefinedEnv struct {
+	Key  string
+	Name string
+}
+
+func (e ErrUndefinedEnv) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("environment variable %q is not defined", e.Name)
+	}
+	return fmt.Sprintf("value for the key %q references the undefined environment variable %q", e.Key, e.Name)
+}
+
+// lookupFunc returns the value of the named variable, and whether it's
+// defined.
+type lookupFunc func(name string) (string, bool)
+
+// interpolator expands variable references in strings, see interpolate for
+// the supported syntax.
+type interpolator struct {
+	lookup lookupFunc
+
+	// strict makes references to undefined variables fail, unless they
+	// provide a default value.
+	strict bool
+
+	// onRef, when not nil, is called for every variable evaluated during
+	// the expansion. required tells whether the reference has no default
+	// value.
+	onRef func(name string, defined, required bool)
+
+	// allow, when not nil, restricts the variables that can be expanded.
+	// References to other variables are kept verbatim.
+	allow func(name string) bool
+
+	// resolvers expand references in the form ${prefix:argument}, they're
+	// indexed by prefix.
+	resolvers map[string]resolverFunc
+}
+
+// resolverFunc returns the value for the argument of a prefixed reference.
+type resolverFunc func(arg string) (interface{}, error)
+
+type refKind int
+
+const (
+	refPlain refKind = iota
+	refDefault
+	refRequired
+)
+
+// interpolate expands shell-style variable references in s. The supported
+// forms are:
+//
+//   $NAME or ${NAME}   the value of NAME, or an empty string
+//   ${NAME:-default}   default when NAME is unset or empty
+//   ${NAME-default}    default when NAME is unset
+//   ${NAME:?message}   error when NAME is unset or empty
+//   ${NAME?message}    error when NAME is unset
+//   $$                 a literal $
+//
+// Defaults and messages may contain references themselves. Anything that
+// doesn't match one of the forms above is kept untouched.
+func interpolate(s string, lookup lookupFunc) (string, error) {
+	return (&interpolator{lookup: lookup}).expand(s)
+}
+
+func (in *interpolator) expand(s string) (string, error) {
+	if strings.IndexByte(s, '$') < 0 {
+		return s, nil
+	}
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			buf.WriteByte('$')
+			i++
+		case next == '{':
+			end := matchBrace(s, i+1)
+			if end < 0 {
+				buf.WriteString(s[i:])
+				return buf.String(), nil
+			}
+			value, ok, err := in.expandBraces(s[i+2 : end])
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				buf.WriteString(value)
+			} else {
+				buf.WriteString(s[i : end+1])
+			}
+			i = end
+		case isNameChar(next):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			if !in.allowed(s[i+1 : j]) {
+				buf.WriteString(s[i:j])
+				i = j - 1
+				continue
+			}
+			value, _, err := in.variable(s[i+1:j], refPlain)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(value)
+			i = j - 1
+		default:
+			buf.WriteByte('$')
+		}
+	}
+	return buf.String(), nil
+}
+
+// isOperator reports whether s, the text after the colon of a ${...}
+// expression, starts with a shell operator, as in ${config:-default}, so the
+// expression is a variable rather than a reference.
+func isOperator(s string) bool {
+	return s != "" && (s[0] == '-' || s[0] == '?')
+}
+
+// expandBraces expands the contents of a ${...} expression. It returns false
+// if the expression is not valid, so the caller can keep it verbatim.
+func (in *interpolator) expandBraces(expr string) (string, bool, error) {
+	if i := strings.IndexByte(expr, ':'); i > 0 && !isOperator(expr[i+1:]) {
+		if resolve, ok := in.resolvers[expr[:i]]; ok {
+			value, err := resolve(expr[i+1:])
+			if err != nil {
+				return "", true, err
+			}
+			return fmt.Sprint(value), true, nil
+		}
+	}
+	n := 0
+	for n < len(expr) && isNameChar(expr[n]) {
+		n++
+	}
+	if n == 0 {
+		return "", false, nil
+	}
+	name, op := expr[:n], expr[n:]
+	if !in.allowed(name) {
+		return "", false, nil
+	}
+	if op == "" {
+		value, _, err := in.variable(name, refPlain)
+		return value, true, err
+	}
+	var word string
+	checkEmpty := op[0] == ':'
+	if checkEmpty {
+		op = op[1:]
+	}
+	if op == "" || (op[0] != '-' && op[0] != '?') {
+		return "", false, nil
+	}
+	op, word = op[:1], op[1:]
+	kind := refDefault
+	if op == "?" {
+		kind = refRequired
+	}
+	value, defined, err := in.variable(name, kind)
+	if err != nil {
+		return "", true, err
+	}
+	if defined && (!checkEmpty || value != "") {
+		return value, true, nil
+	}
+	if op == "-" {
+		value, err = in.expand(word)
+		return value, true, err
+	}
+	msg, err := in.expand(word)
+	if err != nil {
+		return "", true, err
+	}
+	return "", true, ErrRequiredEnv{Name: name, Message: msg}
+}
+
+// variable returns the value of the named variable, and whether it's defined.
+// It fails in strict mode if the variable is not defined and the reference has
+// neither a default value nor an error message.
+func (in *interpolator) variable(name string, kind refKind) (string, bool, error) {
+	value, defined := in.lookup(name)
+	if in.onRef != nil {
+		in.onRef(name, defined, kind != refDefault)
+	}
+	if in.strict && !defined && kind == refPlain {
+		return "", false, ErrUndefinedEnv{Name: name}
+	}
+	return value, defined, nil
+}
+
+func (in *interpolator) allowed(name string) bool {
+	return in.allow == nil || in.allow(name)
+}
+
+// matchBrace returns the index of the brace that closes the one at s[start],
+// taking nested ${...} expressions and $$ escapes into account. It returns -1
+// if the brace is never closed.
+func matchBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '$':
+			if i+1 < len(s) && (s[i+1] == '$' || s[i+1] == '{') {
+				if s[i+1] == '{' {
+					depth++
+				}
+				i++
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// SetStrictEnv enables or disables the strict mode for environment variables.
+// In strict mode, Get returns an ErrUndefinedEnv error for values referencing
+// environment variables that are not defined, unless the reference provides a
+// default value (${NAME:-default}).
+func SetStrictEnv(strict bool) {
+	DefaultConfig.SetStrictEnv(strict)
+}
+
+func (c *Configuration) SetStrictEnv(strict bool) {
+	c.Lock()
+	defer c.Unlock()
+	c.strictEnv = strict
+}
+
+// SetEnvExpansion enables or disables the expansion of environment variables
+// in values. It's enabled by default. When disabled, Get returns strings
+// verbatim, but still decodes json objects and lists.
+func SetEnvExpansion(enabled bool) {
+	DefaultConfig.SetEnvExpansion(enabled)
+}
+
+func (c *Configuration) SetEnvExpansion(enabled bool) {
+	c.Lock()
+	defer c.Unlock()
+	c.envDisabled = !enabled
+	c.scanEnv(c.data)
+}
+
+// AllowEnv restricts the expansion of environment variables to the given
+// names, plus the ones allowed by previous calls to AllowEnv and
+// AllowEnvPrefix. References to variables that are not allowed are kept
+// verbatim.
+//
+// Restricting the expansion also disables references to other keys, like
+// ${config:database:password}, which could read secrets just as well. Use
+// AllowReferences to enable them again.
+//
+// By default, all variables may be expanded.
+func AllowEnv(names ...string) {
+	DefaultConfig.AllowEnv(names...)
+}
+
+func (c *Configuration) AllowEnv(names ...string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.envAllow == nil {
+		c.envAllow = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		c.envAllow[name] = true
+	}
+	c.scanEnv(c.data)
+}
+
+// AllowEnvPrefix works like AllowEnv, allowing the expansion of all
+// environment variables starting with one of the given prefixes.
+func AllowEnvPrefix(prefixes ...string) {
+	DefaultConfig.AllowEnvPrefix(prefixes...)
+}
+
+func (c *Configuration) AllowEnvPrefix(prefixes ...string) {
+	c.Lock()
+	defer c.Unlock()
+	c.envPrefixes = append(c.envPrefixes, prefixes...)
+	c.scanEnv(c.data)
+}
+
+// AllowReferences enables references with the given prefixes, "file" for
+// files and "config" for other keys. References to files are disabled by
+// default, as any value could read any file readable by the process.
+// References to other keys are disabled when the expansion of environment
+// variables is restricted by AllowEnv or AllowEnvPrefix. For example:
+//
+//   config.AllowEnv("PORT")
+//   config.AllowReferences("config", "file")
+//
+// References that are not allowed are kept verbatim.
+func AllowReferences(prefixes ...string) {
+	DefaultConfig.AllowReferences(prefixes...)
+}
+
+func (c *Configuration) AllowReferences(prefixes ...string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.refAllow == nil {
+		c.refAllow = make(map[string]bool, len(prefixes))
+	}
+	for _, prefix := range prefixes {
+		c.refAllow[prefix] = true
+	}
+	c.scanFiles(c.data)
+}
+
+// SetRaw marks the given keys, and all keys below them, as raw: Get returns
+// their values verbatim, without expanding environment variables nor decoding
+// json.
+func SetRaw(keys ...string) {
+	DefaultConfig.SetRaw(keys...)
+}
+
+func (c *Configuration) SetRaw(keys ...string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.rawKeys == nil {
+		c.rawKeys = make(map[string]bool, len(keys))
+	}
+	for _, key := range keys {
+		c.rawKeys[key] = true
+	}
+	c.scanEnv(c.data)
+}
+
+// EnvReferences returns the environment variables referenced by values in the
+// configuration, mapped to the keys that reference them.
+func EnvReferences() map[string][]string {
+	return DefaultConfig.EnvReferences()
+}
+
+func (c *Configuration) EnvReferences() map[string][]string {
+	c.RLock()
+	defer c.RUnlock()
+	return copyRefs(c.envRefs)
+}
+
+// UndefinedEnv returns the environment variables that were referenced without
+// a default value and were not defined when the configuration was loaded,
+// mapped to the keys that reference them.
+func UndefinedEnv() map[string][]string {
+	return DefaultConfig.UndefinedEnv()
+}
+
+func (c *Configuration) UndefinedEnv() map[string][]string {
+	c.RLock()
+	defer c.RUnlock()
+	return copyRefs(c.undefinedEnv)
+}
+
+// CheckEnv is a Checker that fails if any environment variable reported by
+// UndefinedEnv was not defined when the configuration was loaded.
+func CheckEnv() error {
+	return DefaultConfig.CheckEnv()
+}
+
+func (c *Configuration) CheckEnv() error {
+	undefined := c.UndefinedEnv()
+	if len(undefined) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(undefined))
+	for name := range undefined {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var keyed []ValidationError
+	for i, name := range names {
+		for _, key := range undefined[name] {
+			keyed = append(keyed, ValidationError{Key: key, Message: fmt.Sprintf("undefined environment variable %s", name)})
+		}
+		names[i] = fmt.Sprintf("%s (used by %s)", name, strings.Join(undefined[name], ", "))
+	}
+	return &keyedErr{
+		msg:   fmt.Sprintf("undefined environment variables: %s", strings.Join(names, "; ")),
+		keyed: keyed,
+	}
+}
+
+// expandString expands environment variables and references in s, read from
+// the given key, according to the configuration settings. Encrypted values
+// are decrypted instead, like Get does.
+func (c *Configuration) expandString(key, s string) (string, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.isRaw(key) {
+		return s, nil
+	}
+	if isEncrypted(s) {
+		plain, err := c.decrypt(s)
+		if err != nil {
+			return "", &ErrDecrypt{Key: key, Err: err}
+		}
+		return plain, nil
+	}
+	r := &resolution{}
+	if err := r.enter(key); err != nil {
+		return "", err
+	}
+	return c.interpolate(s, r)
+}
+
+// interpolate works like expandString, but the caller must hold the lock.
+func (c *Configuration) interpolate(s string, r *resolution) (string, error) {
+	if c.envDisabled {
+		return s, nil
+	}
+	in := interpolator{
+		lookup:    os.LookupEnv,
+		strict:    c.strictEnv,
+		allow:     c.envAllowFunc(),
+		resolvers: make(map[string]resolverFunc, 2),
+	}
+	if c.referenceAllowed(referencePrefix) {
+		in.resolvers[referencePrefix] = func(ref string) (interface{}, error) {
+			return c.resolveReference(ref, r)
+		}
+	}
+	if c.referenceAllowed(filePrefix) {
+		in.resolvers[filePrefix] = func(path string) (interface{}, error) {
+			return c.readFileKey("", path)
+		}
+	}
+	return in.expand(s)
+}
+
+// referenceAllowed reports whether references with the given prefix may be
+// expanded. References to files must always be allowed with AllowReferences,
+// references to other keys only when the expansion of environment variables
+// is restricted.
+func (c *Configuration) referenceAllowed(prefix string) bool {
+	if prefix != filePrefix && len(c.envAllow) == 0 && len(c.envPrefixes) == 0 {
+		return true
+	}
+	return c.refAllow[prefix]
+}
+
+// envAllowFunc returns the function that filters the variables that may be
+// expanded, or nil if all of them are allowed.
+func (c *Configuration) envAllowFunc() func(string) bool {
+	if len(c.envAllow) == 0 && len(c.envPrefixes) == 0 {
+		return nil
+	}
+	return func(name string) bool {
+		if c.envAllow[name] {
+			return true
+		}
+		for _, prefix := range c.envPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isRaw reports whether the key, or any of its parents, was marked as raw. All
+// keys of verbatim configurations are raw.
+func (c *Configuration) isRaw(key string) bool {
+	if c.verbatim {
+		return true
+	}
+	if len(c.rawKeys) == 0 {
+		return false
+	}
+	for {
+		if c.rawKeys[key] {
+			return true
+		}
+		i := strings.LastIndexByte(key, ':')
+		if i < 0 {
+			return false
+		}
+		key = key[:i]
+	}
+}
+
+// scanEnv records the environment variables referenced by the values in data.
+func (c *Configuration) scanEnv(data map[interface{}]interface{}) {
+	c.envRefs = make(map[string][]string)
+	c.undefinedEnv = make(map[string][]string)
+	if c.envDisabled {
+		return
+	}
+	walkValues(data, "", func(key string, value interface{}) {
+		s, ok := value.(string)
+		if !ok || c.isRaw(key) {
+			return
+		}
+		in := interpolator{lookup: os.LookupEnv, allow: c.envAllowFunc()}
+		in.onRef = func(name string, defined, required bool) {
+			c.envRefs[name] = appendKey(c.envRefs[name], key)
+			if !defined && required {
+				c.undefinedEnv[name] = appendKey(c.undefinedEnv[name], key)
+			}
+		}
+		if _, err := in.expand(s); err != nil {
+			if e, ok := err.(ErrRequiredEnv); ok {
+				c.undefinedEnv[e.Name] = appendKey(c.undefinedEnv[e.Name], key)
+			}
+		}
+	})
+}
+
+// walkValues calls fn for every leaf value in the tree, using the colon
+// separated key that leads to it. Items in lists use the key of the list.
+func walkValues(data map[interface{}]interface{}, prefix string, fn func(key string, value interface{})) {
+	for k, v := range data {
+		walkValue(v, joinKey(prefix, k), fn)
+	}
+}
+
+// joinKey returns the key for the child k of the given parent key.
+func joinKey(parent string, k interface{}) string {
+	if parent == "" {
+		return fmt.Sprint(k)
+	}
+	return parent + ":" + fmt.Sprint(k)
+}
+
+func walkValue(value interface{}, key string, fn func(key string, value interface{})) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		walkValues(v, key, fn)
+	case []interface{}:
+		for _, item := range v {
+			walkValue(item, key, fn)
+		}
+	default:
+		fn(key, value)
+	}
+}
+
+func appendKey(keys []string, key string) []string {
+	for _, k := range keys {
+		if k == key {
+			return keys
+		}
+	}
+	keys = append(keys, key)
+	sort.Strings(keys)
+	return keys
+}
+
+func copyRefs(refs map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(refs))
+	for name, keys := range refs {
+		result[name] = append([]string(nil), keys...)
+	}
+	return result
+}
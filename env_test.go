@@ -0,0 +1,310 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+func fakeLookup(vars map[string]string) lookupFunc {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func (s *S) TestInterpolate(c *check.C) {
+	lookup := fakeLookup(map[string]string{
+		"HOST":  "localhost",
+		"PORT":  "8080",
+		"EMPTY": "",
+	})
+	var tests = []struct {
+		input    string
+		expected string
+	}{
+		{"plain value", "plain value"},
+		{"$HOST", "localhost"},
+		{"${HOST}:${PORT}", "localhost:8080"},
+		{"http://$HOST:$PORT/", "http://localhost:8080/"},
+		{"$UNDEFINED", ""},
+		{"${UNDEFINED:-8000}", "8000"},
+		{"${UNDEFINED-8000}", "8000"},
+		{"${EMPTY:-8000}", "8000"},
+		{"${EMPTY-8000}", ""},
+		{"${PORT:-8000}", "8080"},
+		{"${UNDEFINED:-${HOST}:${PORT}}", "localhost:8080"},
+		{"${UNDEFINED:-${OTHER:-${PORT}}}", "8080"},
+		{"${UNDEFINED:-{\"a\": 1}}", "{\"a\": 1}"},
+		{"$$HOST", "$HOST"},
+		{"price: $$10", "price: $10"},
+		{"${UNDEFINED:-$$}", "$"},
+		{"100$", "100$"},
+		{"a $ b", "a $ b"},
+		{"${HOST", "${HOST"},
+		{"${}", "${}"},
+		{"${HOST:}", "${HOST:}"},
+		{"${HOST+x}", "${HOST+x}"},
+		{"${PORT:?must be set}", "8080"},
+	}
+	for _, t := range tests {
+		value, err := interpolate(t.input, lookup)
+		c.Check(err, check.IsNil)
+		c.Check(value, check.Equals, t.expected, check.Commentf("input: %q", t.input))
+	}
+}
+
+func (s *S) TestInterpolateRequired(c *check.C) {
+	lookup := fakeLookup(map[string]string{"EMPTY": "", "WHY": "needed"})
+	_, err := interpolate("${MONGOURI:?must be set}", lookup)
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Name: "MONGOURI", Message: "must be set"})
+	_, err = interpolate("${EMPTY:?}", lookup)
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Name: "EMPTY"})
+	value, err := interpolate("${EMPTY?}", lookup)
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "")
+	_, err = interpolate("${MONGOURI?$WHY}", lookup)
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Name: "MONGOURI", Message: "needed"})
+	_, err = interpolate("${UNDEFINED:-${MONGOURI:?}}", lookup)
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Name: "MONGOURI"})
+}
+
+func (s *S) TestGetConfigExpandVarsWithDefault(c *check.C) {
+	os.Unsetenv("DBPORT")
+	Set("database:port", "${DBPORT:-27017}")
+	value, err := GetInt("database:port")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 27017)
+	os.Setenv("DBPORT", "6680")
+	defer os.Unsetenv("DBPORT")
+	value, err = GetInt("database:port")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, 6680)
+}
+
+func (s *S) TestGetConfigExpandVarsEscaping(c *check.C) {
+	Set("password", "pa$$word")
+	value, err := GetString("password")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "pa$word")
+}
+
+func (s *S) TestGetConfigExpandVarsDefaultJsonObject(c *check.C) {
+	os.Unsetenv("DATABASE")
+	Set("database", `${DATABASE:-{"host": "6.6.6.6"}}`)
+	value, err := GetString("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "6.6.6.6")
+}
+
+func (s *S) TestGetConfigExpandVarsRequired(c *check.C) {
+	os.Unsetenv("MONGOURI")
+	Set("mongo", "${MONGOURI:?must be set}")
+	value, err := Get("mongo")
+	c.Assert(value, check.IsNil)
+	c.Assert(err, check.FitsTypeOf, ErrRequiredEnv{})
+	c.Assert(err.Error(), check.Equals, `value for the key "mongo" requires the environment variable "MONGOURI": must be set`)
+	_, err = GetString("mongo")
+	c.Assert(err, check.FitsTypeOf, ErrRequiredEnv{})
+	os.Setenv("MONGOURI", "localhost/test")
+	defer os.Unsetenv("MONGOURI")
+	value, err = Get("mongo")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "localhost/test")
+}
+
+func (s *S) TestGetConfigExpandVarsRequiredInPath(c *check.C) {
+	os.Unsetenv("DATABASE")
+	Set("database", "${DATABASE:?}")
+	_, err := Get("database:host")
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Key: "database:host", Name: "DATABASE"})
+}
+
+func (s *S) TestGetListExpandVarsRequired(c *check.C) {
+	os.Unsetenv("DBHOST")
+	Set("databases", []interface{}{"${DBHOST:?}", "host2"})
+	_, err := GetList("databases")
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Key: "databases", Name: "DBHOST"})
+}
+
+func (s *S) TestStrictEnv(c *check.C) {
+	os.Unsetenv("DBHOST")
+	os.Unsetenv("DBPORT")
+	var conf Configuration
+	err := conf.ReadConfigFile("testdata/config3.yml")
+	c.Assert(err, check.IsNil)
+	value, err := conf.Get("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "")
+	conf.SetStrictEnv(true)
+	value, err = conf.Get("database:host")
+	c.Assert(value, check.IsNil)
+	c.Assert(err, check.DeepEquals, ErrUndefinedEnv{Key: "database:host", Name: "DBHOST"})
+	c.Assert(err.Error(), check.Equals, `value for the key "database:host" references the undefined environment variable "DBHOST"`)
+	_, err = conf.GetList("databases")
+	c.Assert(err, check.DeepEquals, ErrUndefinedEnv{Key: "databases", Name: "DBHOST"})
+	os.Setenv("DBHOST", "6.6.6.6")
+	defer os.Unsetenv("DBHOST")
+	value, err = conf.Get("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "6.6.6.6")
+}
+
+func (s *S) TestStrictEnvWithDefaults(c *check.C) {
+	os.Unsetenv("DBPORT")
+	var conf Configuration
+	conf.SetStrictEnv(true)
+	conf.Set("port", "${DBPORT:-8080}")
+	conf.Set("empty", "${DBPORT-}")
+	value, err := conf.Get("port")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "8080")
+	value, err = conf.Get("empty")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "")
+	conf.Set("required", "${DBPORT:?must be set}")
+	_, err = conf.Get("required")
+	c.Assert(err, check.FitsTypeOf, ErrRequiredEnv{})
+}
+
+func (s *S) TestEnvReferences(c *check.C) {
+	os.Setenv("DBHOST", "6.6.6.6")
+	defer os.Unsetenv("DBHOST")
+	os.Unsetenv("DBPORT")
+	os.Unsetenv("MONGOURI")
+	os.Unsetenv("NAME")
+	var conf Configuration
+	err := conf.ReadConfigFile("testdata/config3.yml")
+	c.Assert(err, check.IsNil)
+	conf.Set("mongo", "${MONGOURI:?}")
+	conf.Set("name", "${NAME:-tsuru}")
+	c.Assert(conf.EnvReferences(), check.DeepEquals, map[string][]string{
+		"DBHOST":   {"database:host", "databases"},
+		"DBPORT":   {"database:port"},
+		"MONGOURI": {"mongo"},
+		"NAME":     {"name"},
+	})
+	c.Assert(conf.UndefinedEnv(), check.DeepEquals, map[string][]string{
+		"DBPORT":   {"database:port"},
+		"MONGOURI": {"mongo"},
+	})
+}
+
+func (s *S) TestCheckEnv(c *check.C) {
+	os.Unsetenv("DBHOST")
+	os.Unsetenv("DBPORT")
+	err := ReadConfigFile("testdata/config3.yml")
+	c.Assert(err, check.IsNil)
+	err = Check([]Checker{CheckEnv})
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "undefined environment variables: DBHOST (used by database:host, databases); DBPORT (used by database:port)")
+	os.Setenv("DBHOST", "6.6.6.6")
+	defer os.Unsetenv("DBHOST")
+	os.Setenv("DBPORT", "27017")
+	defer os.Unsetenv("DBPORT")
+	err = ReadConfigFile("testdata/config3.yml")
+	c.Assert(err, check.IsNil)
+	c.Assert(CheckEnv(), check.IsNil)
+}
+
+func (s *S) TestSetEnvExpansion(c *check.C) {
+	os.Setenv("DBHOST", "6.6.6.6")
+	defer os.Unsetenv("DBHOST")
+	var conf Configuration
+	err := conf.ReadConfigFile("testdata/config3.yml")
+	c.Assert(err, check.IsNil)
+	conf.Set("json", `{"host": "$DBHOST"}`)
+	conf.SetEnvExpansion(false)
+	value, err := conf.Get("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "$DBHOST")
+	values, err := conf.GetList("databases")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []string{"$DBHOST", "host2"})
+	value, err = conf.Get("json:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "$DBHOST")
+	c.Assert(conf.EnvReferences(), check.HasLen, 0)
+	conf.SetEnvExpansion(true)
+	value, err = conf.Get("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "6.6.6.6")
+}
+
+func (s *S) TestAllowEnv(c *check.C) {
+	os.Setenv("DBHOST", "6.6.6.6")
+	defer os.Unsetenv("DBHOST")
+	os.Setenv("TSURU_PORT", "8080")
+	defer os.Unsetenv("TSURU_PORT")
+	os.Setenv("SECRET", "s3cr3t")
+	defer os.Unsetenv("SECRET")
+	var conf Configuration
+	conf.Set("host", "$DBHOST")
+	conf.Set("port", "${TSURU_PORT}")
+	conf.Set("leak", "$SECRET and ${SECRET:-x}")
+	conf.AllowEnv("DBHOST")
+	conf.AllowEnvPrefix("TSURU_")
+	value, err := conf.Get("host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "6.6.6.6")
+	value, err = conf.Get("port")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "8080")
+	value, err = conf.Get("leak")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "$SECRET and ${SECRET:-x}")
+	c.Assert(conf.EnvReferences(), check.DeepEquals, map[string][]string{
+		"DBHOST":     {"host"},
+		"TSURU_PORT": {"port"},
+	})
+}
+
+func (s *S) TestAllowEnvDisablesReferences(c *check.C) {
+	path := writeSecret(c, c.MkDir(), "db_password", "s3cr3t")
+	var conf Configuration
+	conf.Set("database:password", "hunter2")
+	conf.Set("user", "${file:"+path+"} ${config:database:password}")
+	conf.AllowEnv("PUBLIC")
+	value, err := conf.Get("user")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "${file:"+path+"} ${config:database:password}")
+	conf.AllowReferences("config")
+	value, err = conf.Get("user")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "${file:"+path+"} hunter2")
+	conf.AllowReferences("file")
+	value, err = conf.Get("user")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "s3cr3t hunter2")
+}
+
+func (s *S) TestSetRaw(c *check.C) {
+	os.Setenv("GOPHER_HOME", "/home/gopher")
+	defer os.Unsetenv("GOPHER_HOME")
+	os.Setenv("DATABASE", `{"host": "6.6.6.6"}`)
+	defer os.Unsetenv("DATABASE")
+	var conf Configuration
+	conf.Set("apps:myapp:description", "uses $GOPHER_HOME")
+	conf.Set("apps:myapp:env", []interface{}{"$GOPHER_HOME"})
+	conf.Set("apps:other", "$GOPHER_HOME")
+	conf.Set("database", "$DATABASE")
+	conf.SetRaw("apps:myapp", "database")
+	value, err := conf.Get("apps:myapp:description")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "uses $GOPHER_HOME")
+	values, err := conf.GetList("apps:myapp:env")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []string{"$GOPHER_HOME"})
+	value, err = conf.Get("apps:other")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "/home/gopher")
+	value, err = conf.Get("database")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "$DATABASE")
+	_, err = conf.Get("database:host")
+	c.Assert(err, check.Equals, ErrMismatchConf)
+}
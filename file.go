@@ -0,0 +1,109 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	// filePrefix is the prefix of references to files in values, for
+	// example: ${file:/run/secrets/db_password}.
+	filePrefix = "file"
+
+	// fileKeySuffix is the suffix of keys that hold the path of a file
+	// containing the value for the key without the suffix.
+	fileKeySuffix = "_file"
+)
+
+// readFile returns the contents of the file in the given path, without
+// trailing line breaks. Contents are cached until the configuration is
+// reloaded or, when watching the configuration file, until the file changes.
+func (c *Configuration) readFile(path string) (interface{}, error) {
+	c.filesMu.Lock()
+	defer c.filesMu.Unlock()
+	if content, ok := c.files[path]; ok {
+		return content, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimRight(string(data), "\r\n")
+	if c.files == nil {
+		c.files = make(map[string]string)
+	}
+	c.files[path] = content
+	return content, nil
+}
+
+// readFileKey returns the contents of the file whose path is the value of the
+// given key. Errors are reported as an ErrReference.
+func (c *Configuration) readFileKey(key string, path interface{}) (interface{}, error) {
+	p, ok := path.(string)
+	if !ok {
+		return nil, &InvalidValue{key, "file path"}
+	}
+	content, err := c.readFile(p)
+	if err != nil {
+		return nil, &ErrReference{Key: key, Ref: p, Err: err}
+	}
+	return content, nil
+}
+
+func (c *Configuration) forgetFile(path string) {
+	c.filesMu.Lock()
+	defer c.filesMu.Unlock()
+	delete(c.files, path)
+}
+
+// scanFiles records the files referenced by the values in data, and drops
+// cached contents.
+func (c *Configuration) scanFiles(data map[interface{}]interface{}) {
+	c.filesMu.Lock()
+	c.files = nil
+	c.filesMu.Unlock()
+	c.filePaths = nil
+	walkValues(data, "", func(key string, value interface{}) {
+		s, ok := value.(string)
+		if !ok || c.isRaw(key) {
+			return
+		}
+		if strings.HasSuffix(key, fileKeySuffix) {
+			if path, err := c.interpolate(s, &resolution{}); err == nil {
+				c.filePaths = append(c.filePaths, path)
+			}
+			return
+		}
+		if !c.referenceAllowed(filePrefix) {
+			return
+		}
+		in := interpolator{
+			lookup: os.LookupEnv,
+			resolvers: map[string]resolverFunc{
+				referencePrefix: func(string) (interface{}, error) { return "", nil },
+				filePrefix: func(path string) (interface{}, error) {
+					c.filePaths = append(c.filePaths, path)
+					return "", nil
+				},
+			},
+		}
+		in.expand(s)
+	})
+	c.watchFiles()
+}
+
+// watchFiles adds the referenced files to the watcher, if the configuration
+// file is being watched.
+func (c *Configuration) watchFiles() {
+	if c.watcher == nil {
+		return
+	}
+	for _, path := range c.filePaths {
+		c.watcher.Watch(path)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchema generates a JSON Schema describing the registered keys, with
+// their types, defaults, descriptions and accepted values. The schema can be
+// used by editors and by ValidateSchema.
+//
+// Required keys without a default are required in the schema, as well as the
+// sections holding them. Keys that are not registered are allowed. Enums that
+// ignore case are not listed, as JSON Schema enums are case sensitive.
+func (r *Registry) JSONSchema() ([]byte, error) {
+	root := schemaObject()
+	root["$schema"] = schemaDraft
+	for _, key := range r.Keys() {
+		parts := strings.Split(key.Name, ":")
+		node := root
+		for i, part := range parts[:len(parts)-1] {
+			properties := node["properties"].(map[string]interface{})
+			child, ok := properties[part].(map[string]interface{})
+			if !ok {
+				child = schemaObject()
+				properties[part] = child
+			} else if _, ok = child["properties"]; !ok {
+				child["type"] = "object"
+				child["properties"] = map[string]interface{}{}
+			}
+			if key.Required && key.Default == nil {
+				requireProperty(node, parts[i])
+			}
+			node = child
+		}
+		name := parts[len(parts)-1]
+		node["properties"].(map[string]interface{})[name] = keySchema(key)
+		if key.Required && key.Default == nil {
+			requireProperty(node, name)
+		}
+	}
+	return json.MarshalIndent(root, "", "  ")
+}
+
+func schemaObject() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func requireProperty(node map[string]interface{}, name string) {
+	required, _ := node["required"].([]string)
+	for _, r := range required {
+		if r == name {
+			return
+		}
+	}
+	required = append(required, name)
+	sort.Strings(required)
+	node["required"] = required
+}
+
+func keySchema(key Key) map[string]interface{} {
+	schema := map[string]interface{}{}
+	switch key.Type {
+	case String:
+		schema["type"] = "string"
+	case Int:
+		schema["type"] = "integer"
+	case Uint:
+		schema["type"] = "integer"
+		schema["minimum"] = 0
+	case Float:
+		schema["type"] = "number"
+	case Bool:
+		schema["type"] = "boolean"
+	case Duration:
+		schema["type"] = []string{"string", "number"}
+	case List:
+		schema["type"] = "array"
+	case Map:
+		schema["type"] = "object"
+	}
+	if key.Doc != "" {
+		schema["description"] = key.Doc
+	}
+	if key.Default != nil {
+		schema["default"] = toJSONValue(defaultValue(key.Default))
+	}
+	if len(key.Enum.Values) > 0 && !key.Enum.IgnoreCase {
+		schema["enum"] = key.Enum.Values
+	}
+	return schema
+}
+
+// defaultValue converts default values to the format used in configuration
+// files.
+func defaultValue(value interface{}) interface{} {
+	if d, ok := value.(time.Duration); ok {
+		return d.String()
+	}
+	return value
+}
+
+// Markdown generates a reference of the registered keys, as a Markdown
+// table listing their names, types, defaults and descriptions.
+func (r *Registry) Markdown() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("| Key | Type | Default | Required | Description |\n")
+	buf.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, key := range r.Keys() {
+		def := ""
+		if key.Default != nil {
+			def = "`" + formatDefault(key.Default) + "`"
+		}
+		required := ""
+		if key.Required {
+			required = "yes"
+		}
+		fmt.Fprintf(&buf, "| `%s` | %s | %s | %s | %s |\n", key.Name, key.Type, def, required, markdownCell(keyDoc(key)))
+	}
+	return buf.Bytes()
+}
+
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
+
+// keyDoc returns the description of the key, including its accepted values.
+func keyDoc(key Key) string {
+	doc := key.Doc
+	if len(key.Enum.Values) > 0 {
+		if doc != "" && !strings.HasSuffix(doc, ".") {
+			doc += "."
+		}
+		doc = strings.TrimSpace(doc + " One of: " + strings.Join(key.Enum.Values, ", ") + ".")
+		if key.Enum.IgnoreCase {
+			doc = strings.TrimSuffix(doc, ".") + " (case insensitive)."
+		}
+	}
+	return doc
+}
+
+// formatDefault formats a default value as YAML, in a single line.
+func formatDefault(value interface{}) string {
+	value = defaultValue(value)
+	switch value.(type) {
+	case map[interface{}]interface{}, map[string]interface{}, []interface{}, []string:
+		if b, err := json.Marshal(toJSONValue(toInfValue(value))); err == nil {
+			return string(b)
+		}
+	}
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
+
+func toInfValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return toInfMap(v)
+	case []string:
+		result := make([]interface{}, len(v))
+		for i, s := range v {
+			result[i] = s
+		}
+		return result
+	}
+	return value
+}
+
+// SampleConfig generates an annotated sample configuration file, in YAML,
+// with every registered key. Each key is preceded by comments with its
+// description, type and accepted values. Keys with a default are set to it,
+// while the others are commented out. For example:
+//
+//   database:
+//     # Address of the MongoDB server.
+//     # Type: string. Required.
+//     # url:
+//     # Name of the database.
+//     # Type: string.
+//     name: tsuru
+func (r *Registry) SampleConfig() []byte {
+	var buf bytes.Buffer
+	var section []string
+	for _, key := range r.Keys() {
+		parts := strings.Split(key.Name, ":")
+		common := 0
+		for common < len(section) && common < len(parts)-1 && section[common] == parts[common] {
+			common++
+		}
+		for i := common; i < len(parts)-1; i++ {
+			fmt.Fprintf(&buf, "%s%s:\n", strings.Repeat("  ", i), parts[i])
+		}
+		section = parts[:len(parts)-1]
+		indent := strings.Repeat("  ", len(section))
+		if doc := keyDoc(key); doc != "" {
+			for _, line := range strings.Split(doc, "\n") {
+				fmt.Fprintf(&buf, "%s# %s\n", indent, line)
+			}
+		}
+		info := "Type: " + key.Type.String() + "."
+		if key.Required {
+			info += " Required."
+		}
+		fmt.Fprintf(&buf, "%s# %s\n", indent, info)
+		name := parts[len(parts)-1]
+		if key.Default != nil {
+			fmt.Fprintf(&buf, "%s%s: %s\n", indent, name, formatDefault(key.Default))
+		} else {
+			fmt.Fprintf(&buf, "%s# %s:\n", indent, name)
+		}
+	}
+	return buf.Bytes()
+}
+
+// GenerateFile writes the documentation of the registered keys to the given
+// path, choosing the format by the file extension: JSON Schema for ".json",
+// Markdown for ".md" and a sample configuration for ".yml", ".yaml" and
+// ".conf".
+//
+// It's meant to be called from programs run by go generate, after the
+// packages declaring keys are imported, for example:
+//
+//   //go:generate go run gendocs.go docs/reference/config.md
+//
+// Where gendocs.go is:
+//
+//   //go:build ignore
+//
+//   package main
+//
+//   import (
+//       "log"
+//       "os"
+//
+//       "github.com/tsuru/config"
+//       _ "github.com/tsuru/tsuru/api"
+//   )
+//
+//   func main() {
+//       if err := config.DefaultRegistry.GenerateFile(os.Args[1]); err != nil {
+//           log.Fatal(err)
+//       }
+//   }
+func (r *Registry) GenerateFile(path string) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err = r.JSONSchema()
+		data = append(data, '\n')
+	case ".md":
+		data = r.Markdown()
+	case ".yml", ".yaml", ".conf":
+		data = r.SampleConfig()
+	default:
+		return fmt.Errorf("unknown documentation format for %s", path)
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,196 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func newDocsRegistry() *Registry {
+	var r Registry
+	r.Register(Key{Name: "database:url", Type: String, Required: true, Doc: "Address of the MongoDB server."})
+	r.Register(Key{Name: "database:name", Type: String, Default: "tsuru", Doc: "Name of the database."})
+	r.Register(Key{Name: "provisioner", Type: String, Default: "docker", Enum: Enum{Values: []string{"docker", "kubernetes"}}})
+	r.Register(Key{Name: "auth:token-expire", Type: Duration, Default: 24 * time.Hour})
+	r.Register(Key{Name: "hosts", Type: List, Default: []string{"a", "b"}, Doc: "Hosts | addresses"})
+	r.Register(Key{Name: "workers", Type: Uint, Default: 4})
+	return &r
+}
+
+func (s *S) TestRegistryJSONSchema(c *check.C) {
+	data, err := newDocsRegistry().JSONSchema()
+	c.Assert(err, check.IsNil)
+	var schema map[string]interface{}
+	err = json.Unmarshal(data, &schema)
+	c.Assert(err, check.IsNil)
+	c.Assert(schema["$schema"], check.Equals, schemaDraft)
+	c.Assert(schema["required"], check.DeepEquals, []interface{}{"database"})
+	properties := schema["properties"].(map[string]interface{})
+	database := properties["database"].(map[string]interface{})
+	c.Assert(database["required"], check.DeepEquals, []interface{}{"url"})
+	c.Assert(database["properties"], check.DeepEquals, map[string]interface{}{
+		"url":  map[string]interface{}{"type": "string", "description": "Address of the MongoDB server."},
+		"name": map[string]interface{}{"type": "string", "description": "Name of the database.", "default": "tsuru"},
+	})
+	c.Assert(properties["provisioner"], check.DeepEquals, map[string]interface{}{
+		"type":    "string",
+		"default": "docker",
+		"enum":    []interface{}{"docker", "kubernetes"},
+	})
+	auth := properties["auth"].(map[string]interface{})
+	c.Assert(auth["required"], check.IsNil)
+	c.Assert(auth["properties"], check.DeepEquals, map[string]interface{}{
+		"token-expire": map[string]interface{}{"type": []interface{}{"string", "number"}, "default": "24h0m0s"},
+	})
+	c.Assert(properties["workers"], check.DeepEquals, map[string]interface{}{"type": "integer", "minimum": float64(0), "default": float64(4)})
+}
+
+func (s *S) TestRegistryJSONSchemaValidates(c *check.C) {
+	schema, err := newDocsRegistry().JSONSchema()
+	c.Assert(err, check.IsNil)
+	var conf Configuration
+	conf.Set("database:url", "127.0.0.1:27017")
+	conf.Set("provisioner", "kubernetes")
+	conf.Set("auth:token-expire", "1h")
+	conf.Set("other", true)
+	c.Assert(conf.ValidateSchema(schema), check.IsNil)
+	conf.Set("provisioner", "swarm")
+	conf.Set("workers", -1)
+	err = conf.ValidateSchema(schema)
+	c.Assert(err, check.FitsTypeOf, &ErrSchema{})
+	violations := err.(*ErrSchema).Violations
+	c.Assert(violations, check.HasLen, 2)
+	c.Assert(violations[0].Key, check.Equals, "provisioner")
+	c.Assert(violations[1].Key, check.Equals, "workers")
+}
+
+func (s *S) TestRegistryJSONSchemaSectionKey(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "queue", Type: Map, Doc: "Queue settings."})
+	r.Register(Key{Name: "queue:host", Type: String, Required: true})
+	data, err := r.JSONSchema()
+	c.Assert(err, check.IsNil)
+	var schema map[string]interface{}
+	err = json.Unmarshal(data, &schema)
+	c.Assert(err, check.IsNil)
+	queue := schema["properties"].(map[string]interface{})["queue"].(map[string]interface{})
+	c.Assert(queue["description"], check.Equals, "Queue settings.")
+	c.Assert(queue["required"], check.DeepEquals, []interface{}{"host"})
+}
+
+func (s *S) TestRegistryMarkdown(c *check.C) {
+	expected := "| Key | Type | Default | Required | Description |\n" +
+		"| --- | --- | --- | --- | --- |\n" +
+		"| `auth:token-expire` | duration | `24h0m0s` |  |  |\n" +
+		"| `database:name` | string | `tsuru` |  | Name of the database. |\n" +
+		"| `database:url` | string |  | yes | Address of the MongoDB server. |\n" +
+		"| `hosts` | list | `[\"a\",\"b\"]` |  | Hosts \\| addresses |\n" +
+		"| `provisioner` | string | `docker` |  | One of: docker, kubernetes. |\n" +
+		"| `workers` | uint | `4` |  |  |\n"
+	c.Assert(string(newDocsRegistry().Markdown()), check.Equals, expected)
+}
+
+func (s *S) TestRegistrySampleConfig(c *check.C) {
+	expected := `auth:
+  # Type: duration.
+  token-expire: 24h0m0s
+database:
+  # Name of the database.
+  # Type: string.
+  name: tsuru
+  # Address of the MongoDB server.
+  # Type: string. Required.
+  # url:
+# Hosts | addresses
+# Type: list.
+hosts: ["a","b"]
+# One of: docker, kubernetes.
+# Type: string.
+provisioner: docker
+# Type: uint.
+workers: 4
+`
+	sample := newDocsRegistry().SampleConfig()
+	c.Assert(string(sample), check.Equals, expected)
+	var conf Configuration
+	err := conf.ReadConfigBytes(sample)
+	c.Assert(err, check.IsNil)
+	timeout, err := conf.GetDuration("auth:token-expire")
+	c.Assert(err, check.IsNil)
+	c.Assert(timeout, check.Equals, 24*time.Hour)
+	hosts, err := conf.GetList("hosts")
+	c.Assert(err, check.IsNil)
+	c.Assert(hosts, check.DeepEquals, []string{"a", "b"})
+}
+
+func (s *S) TestRegistryGenerateFile(c *check.C) {
+	r := newDocsRegistry()
+	dir := c.MkDir()
+	for _, name := range []string{"schema.json", "config.md", "tsuru.conf", "sample.yml"} {
+		path := filepath.Join(dir, name)
+		err := r.GenerateFile(path)
+		c.Assert(err, check.IsNil)
+		data, err := ioutil.ReadFile(path)
+		c.Assert(err, check.IsNil)
+		c.Assert(len(data) > 0, check.Equals, true)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "schema.json"))
+	c.Assert(err, check.IsNil)
+	c.Assert(json.Valid(data), check.Equals, true)
+	data, err = ioutil.ReadFile(filepath.Join(dir, "tsuru.conf"))
+	c.Assert(err, check.IsNil)
+	var sample map[interface{}]interface{}
+	c.Assert(yaml.Unmarshal(data, &sample), check.IsNil)
+	err = r.GenerateFile(filepath.Join(dir, "docs.txt"))
+	c.Assert(err, check.ErrorMatches, `unknown documentation format for .*docs.txt`)
+}
+
+func (s *S) TestRegistryCheckValues(c *check.C) {
+	var conf Configuration
+	conf.Set("database:url", "127.0.0.1:27017")
+	conf.Set("provisioner", "swarm")
+	err := newDocsRegistry().Check(&conf)
+	c.Assert(err, check.ErrorMatches, `value for the key "provisioner" is not a valid value \(one of: docker, kubernetes\)`)
+}
+
+func (s *S) TestRegistryEnumIgnoreCase(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "log:level", Type: String, Enum: Enum{Values: []string{"debug", "info"}, IgnoreCase: true}})
+	var conf Configuration
+	r.SetDefaults(&conf)
+	conf.Set("log:level", "INFO")
+	c.Assert(r.Check(&conf), check.IsNil)
+	level, err := conf.GetEnum("log:level")
+	c.Assert(err, check.IsNil)
+	c.Assert(level, check.Equals, "info")
+	conf.Set("log:level", "trace")
+	c.Assert(r.Check(&conf), check.ErrorMatches, `value for the key "log:level" is not a valid value \(one of: debug, info\)`)
+	c.Assert(conf.CheckEnums(), check.ErrorMatches, `value for the key "log:level" is not a valid value \(one of: debug, info\)`)
+	data, err := r.JSONSchema()
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(string(data), `"enum"`), check.Equals, false)
+	c.Assert(string(r.Markdown()), check.Matches, `(?s).*One of: debug, info \(case insensitive\)\..*`)
+}
+
+func (s *S) TestRegisterKeyWithEnum(c *check.C) {
+	defer func() {
+		DefaultRegistry = Registry{}
+		DefaultConfig.enums = nil
+	}()
+	Register(Key{Name: "provisioner", Type: String, Enum: Enum{Values: []string{"docker", "kubernetes"}}})
+	Set("provisioner", "swarm")
+	c.Assert(CheckEnums(), check.ErrorMatches, `value for the key "provisioner" is not a valid value .*`)
+	Set("provisioner", "docker")
+	value, err := GetEnum("provisioner")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "docker")
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+)
+
+// GetAs returns the value for the given key converted to T, using the same
+// conversions of the typed getters: GetInt for int, GetDuration for
+// time.Duration, GetList for []string and so on. Other types, like structs,
+// are decoded with Unmarshal. A nil configuration reads from DefaultConfig.
+//
+//   port, err := config.GetAs[int](nil, "database:port")
+//   timeout, err := config.GetAs[time.Duration](conf, "api:timeout")
+func GetAs[T any](c *Configuration, key string) (T, error) {
+	if c == nil {
+		c = &DefaultConfig
+	}
+	var result T
+	var err error
+	switch p := any(&result).(type) {
+	case *string:
+		*p, err = c.GetString(key)
+	case *int:
+		*p, err = c.GetInt(key)
+	case *uint:
+		*p, err = c.GetUint(key)
+	case *float64:
+		*p, err = c.GetFloat(key)
+	case *bool:
+		*p, err = c.GetBool(key)
+	case *time.Duration:
+		*p, err = c.GetDuration(key)
+	case *time.Time:
+		*p, err = c.GetTime(key)
+	case *os.FileMode:
+		*p, err = c.GetFileMode(key)
+	case **url.URL:
+		*p, err = c.GetURL(key)
+	case *net.IP:
+		*p, err = c.GetIP(key)
+	case **net.IPNet:
+		*p, err = c.GetIPNet(key)
+	case **regexp.Regexp:
+		*p, err = c.GetRegexp(key)
+	case *[]string:
+		*p, err = c.GetList(key)
+	case *[]int:
+		*p, err = c.GetIntList(key)
+	case *[]float64:
+		*p, err = c.GetFloatList(key)
+	case *[]bool:
+		*p, err = c.GetBoolList(key)
+	case *[]time.Duration:
+		*p, err = c.GetDurationList(key)
+	case *map[string]interface{}:
+		*p, err = c.GetStringMap(key)
+	case *map[string]string:
+		*p, err = c.GetStringMapString(key)
+	case *[]map[string]interface{}:
+		*p, err = c.GetMapList(key)
+	default:
+		err = c.Unmarshal(key, &result)
+	}
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// GetAsOr works like GetAs, but returns the given default value when the key
+// is not defined or is set to null.
+func GetAsOr[T any](c *Configuration, key string, def T) (T, error) {
+	value, err := GetAs[T](c, key)
+	if isMissing(err) {
+		return def, nil
+	}
+	return value, err
+}
@@ -0,0 +1,84 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net/url"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestGetAs(c *check.C) {
+	err := ReadConfigFile("testdata/config2.yml")
+	c.Assert(err, check.IsNil)
+	port, err := GetAs[int](nil, "database:port")
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 8080)
+	uport, err := GetAs[uint](nil, "database:port")
+	c.Assert(err, check.IsNil)
+	c.Assert(uport, check.Equals, uint(8080))
+	fport, err := GetAs[float64](nil, "database:port")
+	c.Assert(err, check.IsNil)
+	c.Assert(fport, check.Equals, 8080.0)
+	host, err := GetAs[string](nil, "database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "127.0.0.1")
+	istrue, err := GetAs[bool](nil, "istrue")
+	c.Assert(err, check.IsNil)
+	c.Assert(istrue, check.Equals, false)
+	interval, err := GetAs[time.Duration](nil, "human-interval")
+	c.Assert(err, check.IsNil)
+	c.Assert(interval, check.Equals, 10*time.Second)
+	names, err := GetAs[[]string](nil, "names")
+	c.Assert(err, check.IsNil)
+	c.Assert(names, check.DeepEquals, []string{"Mary", "John", "Anthony", "Gopher"})
+}
+
+func (s *S) TestGetAsConfiguration(c *check.C) {
+	var conf Configuration
+	conf.Set("api", "https://tsuru.example.com")
+	conf.Set("ports", []interface{}{1, 2})
+	u, err := GetAs[*url.URL](&conf, "api")
+	c.Assert(err, check.IsNil)
+	c.Assert(u.Host, check.Equals, "tsuru.example.com")
+	ports, err := GetAs[[]int](&conf, "ports")
+	c.Assert(err, check.IsNil)
+	c.Assert(ports, check.DeepEquals, []int{1, 2})
+	_, err = GetAs[int](nil, "ports")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "ports"})
+}
+
+func (s *S) TestGetAsStruct(c *check.C) {
+	Set("database:host", "localhost")
+	Set("database:port", 27017)
+	db, err := GetAs[databaseConfig](nil, "database")
+	c.Assert(err, check.IsNil)
+	c.Assert(db.Host, check.Equals, "localhost")
+	c.Assert(db.Port, check.Equals, 27017)
+	ptr, err := GetAs[*databaseConfig](nil, "database")
+	c.Assert(err, check.IsNil)
+	c.Assert(ptr.Port, check.Equals, 27017)
+}
+
+func (s *S) TestGetAsInvalid(c *check.C) {
+	Set("xpto", "ble")
+	value, err := GetAs[int](nil, "xpto")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"xpto", "int"})
+	c.Assert(value, check.Equals, 0)
+}
+
+func (s *S) TestGetAsOr(c *check.C) {
+	Set("database:port", 3306)
+	Set("xpto", "ble")
+	port, err := GetAsOr(nil, "database:port", 8080)
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 3306)
+	timeout, err := GetAsOr(nil, "database:timeout", 5*time.Second)
+	c.Assert(err, check.IsNil)
+	c.Assert(timeout, check.Equals, 5*time.Second)
+	_, err = GetAsOr(nil, "xpto", 10)
+	c.Assert(err, check.DeepEquals, &InvalidValue{"xpto", "int"})
+}
@@ -1,10 +1,15 @@
 module github.com/tsuru/config
 
-go 1.14
+go 1.18
 
 require (
 	github.com/howeyc/fsnotify v0.9.0
-	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f
 	gopkg.in/yaml.v2 v2.3.0
 )
+
+require (
+	github.com/kr/text v0.1.0 // indirect
+	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
+)
@@ -0,0 +1,265 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// GetIntList works like GetList, but converts each item like GetInt does.
+//
+// If an item can not be converted, the returned InvalidValue identifies it by
+// its index, for example: "ports[2]".
+//
+// It returns ErrNullValue if the key is set to null.
+func GetIntList(key string) ([]int, error) {
+	return DefaultConfig.GetIntList(key)
+}
+
+func (c *Configuration) GetIntList(key string) ([]int, error) {
+	items, err := c.listItems(key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int, len(items))
+	lenient := c.lenient()
+	for i, item := range items {
+		v, ok := toInt(item, lenient)
+		if !ok {
+			return nil, &InvalidValue{itemKey(key, i), "int"}
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// GetFloatList works like GetList, but converts each item like GetFloat does.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetFloatList(key string) ([]float64, error) {
+	return DefaultConfig.GetFloatList(key)
+}
+
+func (c *Configuration) GetFloatList(key string) ([]float64, error) {
+	items, err := c.listItems(key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]float64, len(items))
+	for i, item := range items {
+		v, ok := toFloat(item)
+		if !ok {
+			return nil, &InvalidValue{itemKey(key, i), "float"}
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// GetDurationList works like GetList, but converts each item like GetDuration
+// does.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetDurationList(key string) ([]time.Duration, error) {
+	return DefaultConfig.GetDurationList(key)
+}
+
+func (c *Configuration) GetDurationList(key string) ([]time.Duration, error) {
+	items, err := c.listItems(key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]time.Duration, len(items))
+	for i, item := range items {
+		v, ok := toDuration(item)
+		if !ok {
+			return nil, &InvalidValue{itemKey(key, i), "duration"}
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// GetBoolList works like GetList, but converts each item like GetBool does.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetBoolList(key string) ([]bool, error) {
+	return DefaultConfig.GetBoolList(key)
+}
+
+func (c *Configuration) GetBoolList(key string) ([]bool, error) {
+	items, err := c.listItems(key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]bool, len(items))
+	lenient := c.lenient()
+	for i, item := range items {
+		v, ok := toBool(item, lenient)
+		if !ok {
+			return nil, &InvalidValue{itemKey(key, i), "boolean"}
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// GetStringMap works like Get, but returns the section under the given key as
+// a map of strings to values. Nested sections are converted too, and their
+// values are expanded and decrypted like Get does.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetStringMap(key string) (map[string]interface{}, error) {
+	return DefaultConfig.GetStringMap(key)
+}
+
+func (c *Configuration) GetStringMap(key string) (map[string]interface{}, error) {
+	value, err := c.value(key)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, &InvalidValue{key, "map"}
+	}
+	expanded, err := c.expandTree(key, m)
+	if err != nil {
+		return nil, err
+	}
+	return toStrMap(expanded.(map[interface{}]interface{})), nil
+}
+
+// GetStringMapString works like GetStringMap, but converts each value like
+// GetString does, expanding environment variables.
+//
+// If a value can not be converted, the returned InvalidValue identifies it by
+// its full key.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetStringMapString(key string) (map[string]string, error) {
+	return DefaultConfig.GetStringMapString(key)
+}
+
+func (c *Configuration) GetStringMapString(key string) (map[string]string, error) {
+	value, err := c.value(key)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, &InvalidValue{key, "map"}
+	}
+	result := make(map[string]string, len(m))
+	for k, item := range m {
+		itemKey := joinKey(key, k)
+		if s, ok := item.(string); ok {
+			if item, err = c.expandString(itemKey, s); err != nil {
+				return nil, withKey(err, itemKey)
+			}
+		}
+		v, ok := toString(item, c.lenient())
+		if !ok {
+			return nil, &InvalidValue{itemKey, "string"}
+		}
+		result[fmt.Sprint(k)] = v
+	}
+	return result, nil
+}
+
+// GetMapList works like GetList, but returns a list of sections, each one
+// converted like GetStringMap does. For example:
+//
+//   pools:
+//     - name: default
+//       public: true
+//     - name: private
+//
+// It returns ErrNullValue if the key is set to null.
+func GetMapList(key string) ([]map[string]interface{}, error) {
+	return DefaultConfig.GetMapList(key)
+}
+
+func (c *Configuration) GetMapList(key string) ([]map[string]interface{}, error) {
+	items, err := c.listItems(key)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		m, ok := item.(map[interface{}]interface{})
+		if !ok {
+			return nil, &InvalidValue{itemKey(key, i), "map"}
+		}
+		expanded, err := c.expandValue(key, m, true, nil)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = toStrMap(expanded.(map[interface{}]interface{}))
+	}
+	return result, nil
+}
+
+// listItems returns the items of the list under the given key, expanding
+// environment variables in strings.
+func (c *Configuration) listItems(key string) ([]interface{}, error) {
+	value, err := c.value(key)
+	if err != nil {
+		return nil, err
+	}
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = make([]interface{}, len(v))
+		copy(items, v)
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice {
+			return nil, &InvalidValue{key, "list"}
+		}
+		items = make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+	}
+	for i, item := range items {
+		if s, ok := item.(string); ok {
+			if items[i], err = c.expandString(key, s); err != nil {
+				return nil, withKey(err, key)
+			}
+		}
+	}
+	return items, nil
+}
+
+func itemKey(key string, i int) string {
+	return fmt.Sprintf("%s[%d]", key, i)
+}
+
+// toStrMap takes an map[interface{}]interface{} and recursively converts it
+// to an map[string]interface{}, including maps inside lists. It's the inverse
+// of toInfMap.
+func toStrMap(iMap map[interface{}]interface{}) map[string]interface{} {
+	newMap := make(map[string]interface{}, len(iMap))
+	for k, v := range iMap {
+		newMap[fmt.Sprint(k)] = toStrValue(v)
+	}
+	return newMap
+}
+
+func toStrValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		return toStrMap(v)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = toStrValue(item)
+		}
+		return result
+	}
+	return value
+}
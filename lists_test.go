@@ -0,0 +1,190 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+const listsConfig = `
+ports:
+  - 8080
+  - "8081"
+  - 8082.0
+bad-ports:
+  - 8080
+  - 80.5
+  - http
+ratios: [0.5, 1, "2.5"]
+timeouts: [10s, 1e9, 2d, 500]
+flags: [true, false]
+bad-flags: [true, "yes"]
+labels:
+  team: gophers
+  replicas: 3
+  region: $REGION
+bad-labels:
+  nested:
+    key: value
+pools:
+  - name: default
+    public: true
+  - name: private
+    teams: [admin]
+bad-pools:
+  - name: default
+  - private
+`
+
+func (s *S) TestGetIntList(c *check.C) {
+	err := ReadConfigBytes([]byte(listsConfig))
+	c.Assert(err, check.IsNil)
+	values, err := GetIntList("ports")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []int{8080, 8081, 8082})
+	_, err = GetIntList("bad-ports")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"bad-ports[1]", "int"})
+	c.Assert(err.Error(), check.Equals, `value for the key "bad-ports[1]" is not a int`)
+	_, err = GetIntList("labels")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"labels", "list"})
+	_, err = GetIntList("unknown")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "unknown"})
+}
+
+func (s *S) TestGetIntListExpandVars(c *check.C) {
+	os.Setenv("PORT", "9090")
+	defer os.Unsetenv("PORT")
+	Set("ports", []interface{}{"$PORT", 8080})
+	values, err := GetIntList("ports")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []int{9090, 8080})
+	os.Setenv("PORTS", "[10, 20]")
+	defer os.Unsetenv("PORTS")
+	Set("ports", "$PORTS")
+	values, err = GetIntList("ports")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []int{10, 20})
+}
+
+func (s *S) TestGetIntListTypedSlice(c *check.C) {
+	Set("ports", []int{1, 2})
+	values, err := GetIntList("ports")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []int{1, 2})
+}
+
+func (s *S) TestGetFloatList(c *check.C) {
+	err := ReadConfigBytes([]byte(listsConfig))
+	c.Assert(err, check.IsNil)
+	values, err := GetFloatList("ratios")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []float64{0.5, 1, 2.5})
+	_, err = GetFloatList("bad-ports")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"bad-ports[2]", "float"})
+}
+
+func (s *S) TestGetDurationList(c *check.C) {
+	err := ReadConfigBytes([]byte(listsConfig))
+	c.Assert(err, check.IsNil)
+	values, err := GetDurationList("timeouts")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []time.Duration{10 * time.Second, time.Second, 48 * time.Hour, 500})
+	_, err = GetDurationList("bad-ports")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"bad-ports[2]", "duration"})
+}
+
+func (s *S) TestGetBoolList(c *check.C) {
+	err := ReadConfigBytes([]byte(listsConfig))
+	c.Assert(err, check.IsNil)
+	values, err := GetBoolList("flags")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []bool{true, false})
+	_, err = GetBoolList("bad-flags")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"bad-flags[1]", "boolean"})
+}
+
+func (s *S) TestGetStringMap(c *check.C) {
+	err := ReadConfigBytes([]byte(listsConfig))
+	c.Assert(err, check.IsNil)
+	value, err := GetStringMap("bad-labels")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.DeepEquals, map[string]interface{}{
+		"nested": map[string]interface{}{"key": "value"},
+	})
+	_, err = GetStringMap("ports")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"ports", "map"})
+	_, err = GetStringMap("unknown")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "unknown"})
+}
+
+func (s *S) TestGetStringMapExpandsValues(c *check.C) {
+	os.Setenv("ZZHOST", "6.6.6.6")
+	defer os.Unsetenv("ZZHOST")
+	var conf Configuration
+	err := conf.LoadKeyFile(newKeyFile(c))
+	c.Assert(err, check.IsNil)
+	password, err := conf.Encrypt("s3cr3t")
+	c.Assert(err, check.IsNil)
+	conf.Set("database:host", "$ZZHOST")
+	conf.Set("database:auth:password", password)
+	conf.Set("pools", []interface{}{
+		map[interface{}]interface{}{"host": "$ZZHOST", "password": password},
+	})
+	value, err := conf.GetStringMap("database")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.DeepEquals, map[string]interface{}{
+		"host": "6.6.6.6",
+		"auth": map[string]interface{}{"password": "s3cr3t"},
+	})
+	pools, err := conf.GetMapList("pools")
+	c.Assert(err, check.IsNil)
+	c.Assert(pools, check.DeepEquals, []map[string]interface{}{
+		{"host": "6.6.6.6", "password": "s3cr3t"},
+	})
+}
+
+func (s *S) TestGetStringMapExpandVarsJsonObject(c *check.C) {
+	os.Setenv("DATABASE", `{"host": "6.6.6.6", "options": {"ssl": true}}`)
+	defer os.Unsetenv("DATABASE")
+	Set("database", "$DATABASE")
+	value, err := GetStringMap("database")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.DeepEquals, map[string]interface{}{
+		"host":    "6.6.6.6",
+		"options": map[string]interface{}{"ssl": true},
+	})
+}
+
+func (s *S) TestGetStringMapString(c *check.C) {
+	os.Setenv("REGION", "us-east-1")
+	defer os.Unsetenv("REGION")
+	err := ReadConfigBytes([]byte(listsConfig))
+	c.Assert(err, check.IsNil)
+	value, err := GetStringMapString("labels")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.DeepEquals, map[string]string{
+		"team":     "gophers",
+		"replicas": "3",
+		"region":   "us-east-1",
+	})
+	_, err = GetStringMapString("bad-labels")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"bad-labels:nested", "string"})
+}
+
+func (s *S) TestGetMapList(c *check.C) {
+	err := ReadConfigBytes([]byte(listsConfig))
+	c.Assert(err, check.IsNil)
+	value, err := GetMapList("pools")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.DeepEquals, []map[string]interface{}{
+		{"name": "default", "public": true},
+		{"name": "private", "teams": []interface{}{"admin"}},
+	})
+	_, err = GetMapList("bad-pools")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"bad-pools[1]", "map"})
+}
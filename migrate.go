@@ -0,0 +1,168 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// VersionKey is the key holding the version of the configuration format.
+// Files without it are at version 0.
+const VersionKey = "version"
+
+// MigrationFunc changes a configuration from one version of the format to
+// the next, usually by moving keys with Get, Set and Unset.
+type MigrationFunc func(*Configuration) error
+
+type migration struct {
+	from, to int
+	fn       MigrationFunc
+}
+
+// RegisterMigration registers a function that migrates configurations from
+// one version of the format to another. When a configuration is loaded,
+// migrations run in order, starting from the version in the file (see
+// VersionKey), before the configuration is stored. After each migration, the
+// version is set to the target one. For example:
+//
+//   config.RegisterMigration(1, 2, func(c *config.Configuration) error {
+//       value, err := c.Get("docker:registry")
+//       if _, ok := err.(config.ErrKeyNotFound); ok {
+//           return nil
+//       }
+//       if err != nil {
+//           return err
+//       }
+//       c.Set("registry:url", value)
+//       return c.Unset("docker:registry")
+//   })
+//
+// Migration functions receive a standalone configuration holding the loaded
+// tree, which returns values as they're written in the file: environment
+// variables and references are not expanded, encrypted values are not
+// decrypted and keys with the "_file" suffix are not read, so migrated files
+// don't get secrets written into them.
+//
+// RegisterMigration panics if to is not greater than from, or if there is
+// already a migration from the given version.
+func RegisterMigration(from, to int, fn MigrationFunc) {
+	DefaultConfig.RegisterMigration(from, to, fn)
+}
+
+func (c *Configuration) RegisterMigration(from, to int, fn MigrationFunc) {
+	if to <= from {
+		panic(fmt.Sprintf("config: invalid migration from version %d to %d", from, to))
+	}
+	c.Lock()
+	defer c.Unlock()
+	for _, m := range c.migrations {
+		if m.from == from {
+			panic(fmt.Sprintf("config: migration from version %d registered twice", from))
+		}
+	}
+	// migrate iterates over the slice without holding the lock, so it's
+	// never changed in place.
+	migrations := append(append([]migration(nil), c.migrations...), migration{from: from, to: to, fn: fn})
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].from < migrations[j].from })
+	c.migrations = migrations
+}
+
+// migrate runs the registered migrations on the given tree, returning the
+// migrated tree and whether any migration ran.
+func (c *Configuration) migrate(data map[interface{}]interface{}) (map[interface{}]interface{}, bool, error) {
+	c.RLock()
+	migrations := c.migrations
+	c.RUnlock()
+	if len(migrations) == 0 {
+		return data, false, nil
+	}
+	tmp := &Configuration{envDisabled: true, verbatim: true}
+	tmp.store(data)
+	version := 0
+	if _, ok := data[VersionKey]; ok {
+		var err error
+		if version, err = tmp.GetInt(VersionKey); err != nil {
+			return nil, false, err
+		}
+	}
+	migrated := false
+	for _, m := range migrations {
+		if m.from != version {
+			continue
+		}
+		if err := m.fn(tmp); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate the configuration from version %d to %d: %s", m.from, m.to, err)
+		}
+		tmp.Set(VersionKey, m.to)
+		version = m.to
+		migrated = true
+	}
+	return tmp.Data(), migrated, nil
+}
+
+// DryRunMigration reads the configuration file in the given path, runs the
+// registered migrations and writes the resulting YAML, as serialized by
+// Bytes, to w. Neither the file nor the configuration are changed.
+func DryRunMigration(filePath string, w io.Writer) error {
+	return DefaultConfig.DryRunMigration(filePath, w)
+}
+
+func (c *Configuration) DryRunMigration(filePath string, w io.Writer) error {
+	data, _, err := c.readMigrated(filePath)
+	if err != nil {
+		return err
+	}
+	tmp := &Configuration{}
+	tmp.store(data)
+	b, err := tmp.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// MigrateConfigFile reads the configuration file in the given path, runs the
+// registered migrations and, if any of them ran, rewrites the file with the
+// result, using the given permissions. The migrated configuration is stored
+// as well.
+//
+// Rewriting a signed file invalidates its signature, see SignConfigFile.
+func MigrateConfigFile(filePath string, perm os.FileMode) error {
+	return DefaultConfig.MigrateConfigFile(filePath, perm)
+}
+
+func (c *Configuration) MigrateConfigFile(filePath string, perm os.FileMode) error {
+	data, migrated, err := c.readMigrated(filePath)
+	if err != nil {
+		return err
+	}
+	c.Store(data)
+	if !migrated {
+		return nil
+	}
+	return c.replaceConfigFile(filePath, data, perm)
+}
+
+// readMigrated reads and migrates the configuration file in the given path,
+// reporting whether any migration ran.
+func (c *Configuration) readMigrated(filePath string) (map[interface{}]interface{}, bool, error) {
+	raw, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, false, err
+	}
+	if err = c.verifySignature(filePath, raw); err != nil {
+		return nil, false, err
+	}
+	data, err := parseYAML(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	return c.migrate(data)
+}
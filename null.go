@@ -0,0 +1,122 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ErrNullValue is returned by typed getters when the key is explicitly set to
+// null, for example "host: ~", as opposed to ErrKeyNotFound, returned when the
+// key is not defined at all.
+type ErrNullValue struct {
+	Key string
+}
+
+func (e ErrNullValue) Error() string {
+	return fmt.Sprintf("value for the key %q is null", e.Key)
+}
+
+// isMissing reports whether err means that the key has no value, either
+// because it's not defined or because it's set to null.
+func isMissing(err error) bool {
+	switch err.(type) {
+	case ErrKeyNotFound, ErrNullValue:
+		return true
+	}
+	return false
+}
+
+// IsNull reports whether the given key is defined and set to null, either
+// directly or through a reference to another key.
+func IsNull(key string) bool {
+	return DefaultConfig.IsNull(key)
+}
+
+func (c *Configuration) IsNull(key string) bool {
+	value, err := c.Get(key)
+	return err == nil && value == nil
+}
+
+// value works like Get, but returns ErrNullValue for keys set to null.
+func (c *Configuration) value(key string) (interface{}, error) {
+	value, err := c.Get(key)
+	if err == nil && value == nil {
+		return nil, ErrNullValue{Key: key}
+	}
+	return value, err
+}
+
+// MergeConfigBytes merges the given YAML document over the current
+// configuration. Sections are merged recursively and, in case of conflicts,
+// values from the document win. Keys set to null in the document are removed
+// from the configuration, which allows overlays to delete keys defined by the
+// base file:
+//
+//   database:
+//     password: ~
+//
+// If the given slice is not a valid yaml file, MergeConfigBytes returns a
+// non-nil error and the configuration is left unchanged.
+func MergeConfigBytes(data []byte) error {
+	return DefaultConfig.MergeConfigBytes(data)
+}
+
+func (c *Configuration) MergeConfigBytes(data []byte) error {
+	var overlay map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &overlay); err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.store(overlayMaps(c.data, overlay))
+	return nil
+}
+
+// MergeConfigFile reads the content of a file and calls MergeConfigBytes to
+// merge it over the current configuration. Signatures are verified like in
+// ReadConfigFile.
+//
+// Reloads triggered by ReadAndWatchConfigFile replace the whole
+// configuration, discarding merged overlays.
+func MergeConfigFile(filePath string) error {
+	return DefaultConfig.MergeConfigFile(filePath)
+}
+
+func (c *Configuration) MergeConfigFile(filePath string) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if err = c.verifySignature(filePath, data); err != nil {
+		return err
+	}
+	return c.MergeConfigBytes(data)
+}
+
+// overlayMaps works like mergeMaps, but keys set to nil in overlay are removed
+// from the result.
+func overlayMaps(base, overlay map[interface{}]interface{}) map[interface{}]interface{} {
+	result := make(map[interface{}]interface{}, len(base))
+	for k, v := range base {
+		result[k] = v
+	}
+	for k, v := range overlay {
+		if v == nil {
+			delete(result, k)
+			continue
+		}
+		if overlayInner, ok := v.(map[interface{}]interface{}); ok {
+			baseInner, _ := result[k].(map[interface{}]interface{})
+			result[k] = overlayMaps(baseInner, overlayInner)
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+const nullConfig = `
+database:
+  host: ~
+  port: 27017
+debug: null
+`
+
+func (s *S) TestGetNull(c *check.C) {
+	err := ReadConfigBytes([]byte(nullConfig))
+	c.Assert(err, check.IsNil)
+	value, err := Get("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.IsNil)
+	_, err = Get("database:user")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "database:user"})
+}
+
+func (s *S) TestIsNull(c *check.C) {
+	err := ReadConfigBytes([]byte(nullConfig))
+	c.Assert(err, check.IsNil)
+	c.Assert(IsNull("database:host"), check.Equals, true)
+	c.Assert(IsNull("debug"), check.Equals, true)
+	c.Assert(IsNull("database:port"), check.Equals, false)
+	c.Assert(IsNull("database:user"), check.Equals, false)
+	c.Assert(IsNull("database"), check.Equals, false)
+}
+
+func (s *S) TestIsNullReference(c *check.C) {
+	Set("database:host", nil)
+	Set("queue:host", "${config:database:host}")
+	c.Assert(IsNull("queue:host"), check.Equals, true)
+}
+
+func (s *S) TestErrNullValue(c *check.C) {
+	err := ErrNullValue{Key: "database:host"}
+	c.Assert(err.Error(), check.Equals, `value for the key "database:host" is null`)
+}
+
+func (s *S) TestTypedGettersNull(c *check.C) {
+	err := ReadConfigBytes([]byte(nullConfig))
+	c.Assert(err, check.IsNil)
+	getters := map[string]func(string) (interface{}, error){
+		"GetString":          func(k string) (interface{}, error) { return GetString(k) },
+		"GetInt":             func(k string) (interface{}, error) { return GetInt(k) },
+		"GetFloat":           func(k string) (interface{}, error) { return GetFloat(k) },
+		"GetUint":            func(k string) (interface{}, error) { return GetUint(k) },
+		"GetDuration":        func(k string) (interface{}, error) { return GetDuration(k) },
+		"GetBool":            func(k string) (interface{}, error) { return GetBool(k) },
+		"GetList":            func(k string) (interface{}, error) { return GetList(k) },
+		"GetIntList":         func(k string) (interface{}, error) { return GetIntList(k) },
+		"GetFloatList":       func(k string) (interface{}, error) { return GetFloatList(k) },
+		"GetDurationList":    func(k string) (interface{}, error) { return GetDurationList(k) },
+		"GetBoolList":        func(k string) (interface{}, error) { return GetBoolList(k) },
+		"GetStringMap":       func(k string) (interface{}, error) { return GetStringMap(k) },
+		"GetStringMapString": func(k string) (interface{}, error) { return GetStringMapString(k) },
+		"GetMapList":         func(k string) (interface{}, error) { return GetMapList(k) },
+		"GetURL":             func(k string) (interface{}, error) { return GetURL(k) },
+		"GetIP":              func(k string) (interface{}, error) { return GetIP(k) },
+		"GetIPNet":           func(k string) (interface{}, error) { return GetIPNet(k) },
+		"GetHostPort":        func(k string) (interface{}, error) { host, _, err := GetHostPort(k); return host, err },
+		"GetRegexp":          func(k string) (interface{}, error) { return GetRegexp(k) },
+		"GetFileMode":        func(k string) (interface{}, error) { return GetFileMode(k) },
+		"GetTime":            func(k string) (interface{}, error) { return GetTime(k) },
+		"GetByteSize":        func(k string) (interface{}, error) { return GetByteSize(k) },
+		"GetEnum":            func(k string) (interface{}, error) { return GetEnum(k, "localhost") },
+		"GetAs":              func(k string) (interface{}, error) { return GetAs[time.Duration](nil, k) },
+	}
+	for name, get := range getters {
+		_, err := get("database:host")
+		c.Check(err, check.DeepEquals, ErrNullValue{Key: "database:host"}, check.Commentf("%s", name))
+		_, err = get("database:user")
+		c.Check(err, check.Not(check.FitsTypeOf), ErrNullValue{}, check.Commentf("%s", name))
+	}
+}
+
+func (s *S) TestUnmarshalNull(c *check.C) {
+	err := ReadConfigBytes([]byte(nullConfig))
+	c.Assert(err, check.IsNil)
+	var db databaseConfig
+	err = Unmarshal("debug", &db)
+	c.Assert(err, check.DeepEquals, ErrNullValue{Key: "debug"})
+	err = Unmarshal("database", &db)
+	c.Assert(err, check.IsNil)
+	c.Assert(db.Host, check.Equals, "")
+	c.Assert(db.Port, check.Equals, 27017)
+}
+
+func (s *S) TestGetAsOrNull(c *check.C) {
+	err := ReadConfigBytes([]byte(nullConfig))
+	c.Assert(err, check.IsNil)
+	host, err := GetAsOr(nil, "database:host", "localhost")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "localhost")
+}
+
+func (s *S) TestCheckEnumsIgnoresNull(c *check.C) {
+	var conf Configuration
+	conf.RegisterEnum("provisioner", Enum{Values: []string{"docker"}})
+	conf.Set("provisioner", nil)
+	c.Assert(conf.CheckEnums(), check.IsNil)
+}
+
+func (s *S) TestMergeConfigBytes(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+database:
+  host: localhost
+  port: 27017
+  password: secret
+debug: true
+`))
+	c.Assert(err, check.IsNil)
+	err = MergeConfigBytes([]byte(`
+database:
+  host: db.example.com
+  password: ~
+debug: ~
+queue:
+  host: redis
+  password: ~
+`))
+	c.Assert(err, check.IsNil)
+	expected := map[interface{}]interface{}{
+		"database": map[interface{}]interface{}{
+			"host": "db.example.com",
+			"port": 27017,
+		},
+		"queue": map[interface{}]interface{}{
+			"host": "redis",
+		},
+	}
+	c.Assert(DefaultConfig.Data(), check.DeepEquals, expected)
+	_, err = Get("database:password")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "database:password"})
+}
+
+func (s *S) TestMergeConfigBytesInvalidYAML(c *check.C) {
+	Set("database:host", "localhost")
+	err := MergeConfigBytes([]byte("database: [host"))
+	c.Assert(err, check.NotNil)
+	host, err := GetString("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "localhost")
+}
+
+func (s *S) TestMergeConfigFile(c *check.C) {
+	dir := c.MkDir()
+	overlay := filepath.Join(dir, "overlay.yml")
+	err := ioutil.WriteFile(overlay, []byte("database:\n  port: ~\n"), 0644)
+	c.Assert(err, check.IsNil)
+	var conf Configuration
+	err = conf.ReadConfigBytes([]byte(nullConfig))
+	c.Assert(err, check.IsNil)
+	err = conf.MergeConfigFile(overlay)
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.IsNull("database:host"), check.Equals, true)
+	_, err = conf.Get("database:port")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "database:port"})
+	err = conf.MergeConfigFile(filepath.Join(dir, "missing.yml"))
+	c.Assert(os.IsNotExist(err), check.Equals, true)
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const redacted = "***"
+
+// Secret is a string that hides its value when formatted or serialized, so it
+// doesn't leak to logs. Use string(s) to get the actual value.
+type Secret string
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return redacted
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return redacted, nil
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted)
+}
+
+// MarkSensitive marks the keys matching the given patterns as sensitive, so
+// their values are hidden by RedactedBytes, RedactedData and Explain.
+//
+// Patterns use the same colon separated format of keys, and each part may
+// contain the wildcards supported by path.Match, for example:
+// "database:password" or "*:password". Keys below a sensitive key are
+// sensitive too.
+func MarkSensitive(patterns ...string) {
+	DefaultConfig.MarkSensitive(patterns...)
+}
+
+func (c *Configuration) MarkSensitive(patterns ...string) {
+	c.Lock()
+	defer c.Unlock()
+	c.sensitive = append(c.sensitive, patterns...)
+}
+
+// IsSensitive reports whether the given key was marked as sensitive.
+func IsSensitive(key string) bool {
+	return DefaultConfig.IsSensitive(key)
+}
+
+func (c *Configuration) IsSensitive(key string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.isSensitive(key)
+}
+
+func (c *Configuration) isSensitive(key string) bool {
+	parts := strings.Split(key, ":")
+	for _, pattern := range c.sensitive {
+		if matchKey(pattern, parts) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKey reports whether the pattern matches the key, or one of its
+// parents. The key is given already split in parts.
+func matchKey(pattern string, parts []string) bool {
+	patternParts := strings.Split(pattern, ":")
+	if len(patternParts) > len(parts) {
+		return false
+	}
+	for i, p := range patternParts {
+		if ok, err := path.Match(p, parts[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// RedactedData works like Data, but returns a copy of the configuration with
+// the values of sensitive keys, and Secret values, replaced by "***".
+func RedactedData() map[interface{}]interface{} {
+	return DefaultConfig.RedactedData()
+}
+
+func (c *Configuration) RedactedData() map[interface{}]interface{} {
+	c.RLock()
+	defer c.RUnlock()
+	return c.redactMap(c.data, "")
+}
+
+// RedactedBytes works like Bytes, but serializes the result of RedactedData,
+// making it safe to log the configuration.
+func RedactedBytes() ([]byte, error) {
+	return DefaultConfig.RedactedBytes()
+}
+
+func (c *Configuration) RedactedBytes() ([]byte, error) {
+	return yaml.Marshal(c.RedactedData())
+}
+
+func (c *Configuration) redactMap(data map[interface{}]interface{}, prefix string) map[interface{}]interface{} {
+	if data == nil {
+		return nil
+	}
+	result := make(map[interface{}]interface{}, len(data))
+	for k, v := range data {
+		key := joinKey(prefix, k)
+		if c.isSensitive(key) {
+			result[k] = redacted
+		} else {
+			result[k] = c.redactValue(v, key)
+		}
+	}
+	return result
+}
+
+func (c *Configuration) redactValue(value interface{}, key string) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		return c.redactMap(v, key)
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = c.redactValue(item, key)
+		}
+		return result
+	case Secret:
+		return redacted
+	}
+	return value
+}
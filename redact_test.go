@@ -0,0 +1,123 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/check.v1"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func (s *S) TestSecret(c *check.C) {
+	secret := Secret("s3cr3t")
+	c.Assert(secret.String(), check.Equals, "***")
+	c.Assert(fmt.Sprintf("%v %s %#v", secret, secret, secret), check.Equals, "*** *** ***")
+	data, err := yaml.Marshal(map[string]interface{}{"password": secret})
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "password: '***'\n")
+	data, err = json.Marshal(map[string]interface{}{"password": secret})
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, `{"password":"***"}`)
+	c.Assert(string(secret), check.Equals, "s3cr3t")
+}
+
+func (s *S) TestGetSecret(c *check.C) {
+	Set("database:password", Secret("s3cr3t"))
+	Set("tokens", []interface{}{Secret("t0k3n")})
+	value, err := GetString("database:password")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "s3cr3t")
+	values, err := GetList("tokens")
+	c.Assert(err, check.IsNil)
+	c.Assert(values, check.DeepEquals, []string{"t0k3n"})
+}
+
+func (s *S) TestIsSensitive(c *check.C) {
+	var conf Configuration
+	conf.MarkSensitive("database:password", "*:token", "auth")
+	c.Assert(conf.IsSensitive("database:password"), check.Equals, true)
+	c.Assert(conf.IsSensitive("database:host"), check.Equals, false)
+	c.Assert(conf.IsSensitive("docker:token"), check.Equals, true)
+	c.Assert(conf.IsSensitive("token"), check.Equals, false)
+	c.Assert(conf.IsSensitive("auth"), check.Equals, true)
+	c.Assert(conf.IsSensitive("auth:salt"), check.Equals, true)
+	c.Assert(conf.IsSensitive("database"), check.Equals, false)
+}
+
+func (s *S) TestRedactedData(c *check.C) {
+	err := ReadConfigFile("testdata/config.yml")
+	c.Assert(err, check.IsNil)
+	Set("registry:password", Secret("s3cr3t"))
+	Set("tokens", []interface{}{"public", Secret("t0k3n")})
+	MarkSensitive("auth:key", "database:*")
+	defer func() { DefaultConfig.sensitive = nil }()
+	data := RedactedData()
+	c.Assert(data["auth"], check.DeepEquals, map[interface{}]interface{}{
+		"salt": "xpto",
+		"key":  "***",
+	})
+	c.Assert(data["database"], check.DeepEquals, map[interface{}]interface{}{
+		"host": "***",
+		"user": "***",
+		"port": "***",
+	})
+	c.Assert(data["registry"], check.DeepEquals, map[interface{}]interface{}{"password": "***"})
+	c.Assert(data["tokens"], check.DeepEquals, []interface{}{"public", "***"})
+	c.Assert(data["xpto"], check.Equals, "ble")
+	host, err := GetString("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "127.0.0.1")
+}
+
+func (s *S) TestRedactedBytes(c *check.C) {
+	var conf Configuration
+	conf.Set("database:host", "localhost")
+	conf.Set("database:password", "s3cr3t")
+	conf.MarkSensitive("*:password")
+	data, err := conf.RedactedBytes()
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, "database:\n  host: localhost\n  password: '***'\n")
+}
+
+func (s *S) TestExplainRedactsSensitiveKeys(c *check.C) {
+	var conf Configuration
+	conf.Set("database:password", "s3cr3t")
+	conf.Set("database:url", "mongodb://admin:${config:database:password}@localhost")
+	conf.MarkSensitive("database:password")
+	explanation, err := conf.Explain("database:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(explanation, check.Equals, `database:url: "mongodb://admin:${config:database:password}@localhost"
+  database:password: "***"
+result: "***"
+`)
+}
+
+func (s *S) TestExplainRedactsFileKeys(c *check.C) {
+	path := writeSecret(c, c.MkDir(), "db_password", "topsecret")
+	var conf Configuration
+	conf.Set("database:password_file", path)
+	conf.MarkSensitive("database:password")
+	explanation, err := conf.Explain("database:password")
+	c.Assert(err, check.IsNil)
+	c.Assert(explanation, check.Equals, `database:password_file: "***"
+result: "***"
+`)
+}
+
+func (s *S) TestExplainRedactsAliases(c *check.C) {
+	var conf Configuration
+	conf.Set("db:pass", "hunter2")
+	conf.Alias("db:pass", "database:password")
+	conf.MarkSensitive("database:password")
+	explanation, err := conf.Explain("database:password")
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(explanation, "hunter2"), check.Equals, false)
+	c.Assert(explanation, check.Equals, `  db:pass: "***"
+result: "***"
+`)
+}
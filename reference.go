@@ -0,0 +1,154 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// referencePrefix is the prefix of references to other keys in values, for
+// example: ${config:database:host}.
+const referencePrefix = "config"
+
+// ErrReference is returned by Get when a value references another key, or a
+// file, that can not be read.
+type ErrReference struct {
+	Key string
+	Ref string
+	Err error
+}
+
+func (e *ErrReference) Error() string {
+	return fmt.Sprintf("value for the key %q references %q: %s", e.Key, e.Ref, e.Err)
+}
+
+func (e *ErrReference) Unwrap() error {
+	return e.Err
+}
+
+// ErrReferenceCycle is returned by Get when references between keys form a
+// cycle. Chain lists the keys in the cycle, starting and ending with the same
+// key.
+type ErrReferenceCycle struct {
+	Chain []string
+}
+
+func (e *ErrReferenceCycle) Error() string {
+	return fmt.Sprintf("reference cycle: %s", strings.Join(e.Chain, " -> "))
+}
+
+// resolution keeps track of the keys visited while resolving a value.
+type resolution struct {
+	chain   []string
+	tracing bool
+	trace   []traceStep
+}
+
+type traceStep struct {
+	depth int
+	key   string
+	raw   interface{}
+}
+
+// enter adds the key to the chain of keys being resolved, failing if it's
+// already there.
+func (r *resolution) enter(key string) error {
+	for i, k := range r.chain {
+		if k == key {
+			chain := append([]string(nil), r.chain[i:]...)
+			return &ErrReferenceCycle{Chain: append(chain, key)}
+		}
+	}
+	r.chain = append(r.chain, key)
+	return nil
+}
+
+func (r *resolution) leave() {
+	r.chain = r.chain[:len(r.chain)-1]
+}
+
+// step records the raw value found for a key, when tracing.
+func (r *resolution) step(key string, raw interface{}) {
+	if r.tracing {
+		r.trace = append(r.trace, traceStep{depth: len(r.chain) - 1, key: key, raw: raw})
+	}
+}
+
+// singleReference returns the referenced key if s is made of a single
+// reference to another key.
+func singleReference(s string) (string, bool) {
+	prefix := "${" + referencePrefix + ":"
+	if !strings.HasPrefix(s, prefix) || matchBrace(s, 1) != len(s)-1 || isOperator(s[len(prefix):]) {
+		return "", false
+	}
+	return s[len(prefix) : len(s)-1], true
+}
+
+// resolveReference returns the value for the referenced key. The caller must
+// hold the lock.
+func (c *Configuration) resolveReference(ref string, r *resolution) (interface{}, error) {
+	value, err := c.get(ref, r)
+	if err != nil {
+		if _, ok := err.(*ErrReferenceCycle); ok {
+			return nil, err
+		}
+		return nil, &ErrReference{Ref: ref, Err: err}
+	}
+	return value, nil
+}
+
+// Explain describes how the value for the given key is resolved. It lists the
+// raw value of the key and of every key it references, indented by the depth
+// of the reference, followed by the resolved value. For example:
+//
+//   database:url: "mongodb://${config:database:host}/tsuru"
+//     database:host: "${DBHOST:-localhost}"
+//   result: "mongodb://localhost/tsuru"
+//
+// When the value can not be resolved, Explain returns the steps followed up
+// to the failure, along with the error. Values of sensitive keys, and results
+// that depend on them, are redacted (see MarkSensitive).
+func Explain(key string) (string, error) {
+	return DefaultConfig.Explain(key)
+}
+
+func (c *Configuration) Explain(key string) (string, error) {
+	c.RLock()
+	defer c.RUnlock()
+	r := &resolution{tracing: true}
+	value, err := c.get(key, r)
+	var buf strings.Builder
+	// Values read through aliases, or from the file named by a "_file"
+	// key, are as sensitive as the key that was asked for.
+	sensitive := c.isSensitive(key)
+	same := map[string]bool{key: true}
+	for _, alias := range c.aliasesOf(key) {
+		same[alias] = true
+	}
+	for _, step := range r.trace {
+		raw := step.raw
+		stepKey := strings.TrimSuffix(step.key, fileKeySuffix)
+		if c.isSensitive(stepKey) || (sensitive && same[stepKey]) {
+			raw, sensitive = redacted, true
+		}
+		fmt.Fprintf(&buf, "%s%s: %s\n", strings.Repeat("  ", step.depth), step.key, formatValue(raw))
+	}
+	if err != nil {
+		return buf.String(), err
+	}
+	if sensitive {
+		value = redacted
+	}
+	fmt.Fprintf(&buf, "result: %s\n", formatValue(value))
+	return buf.String(), nil
+}
+
+func formatValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,136 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestGetReference(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+database:
+  host: db.example.com
+  port: 27017
+  url: mongodb://${config:database:host}:${config:database:port}/tsuru
+  address: ${config:database:host}
+`))
+	c.Assert(err, check.IsNil)
+	value, err := Get("database:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "mongodb://db.example.com:27017/tsuru")
+	value, err = Get("database:address")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "db.example.com")
+}
+
+func (s *S) TestGetReferenceKeepsType(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+defaults:
+  port: 8080
+  database:
+    host: db.example.com
+port: ${config:defaults:port}
+database: ${config:defaults:database}
+`))
+	c.Assert(err, check.IsNil)
+	port, err := Get("port")
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 8080)
+	host, err := GetString("database:host")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "db.example.com")
+}
+
+func (s *S) TestGetReferenceJsonExpansion(c *check.C) {
+	err := os.Setenv("DATABASE", `{"host": "6.6.6.6", "port": 27017}`)
+	c.Assert(err, check.IsNil)
+	defer os.Unsetenv("DATABASE")
+	Set("database", "$DATABASE")
+	Set("mongo:url", "mongodb://${config:database:host}/tsuru")
+	Set("mongo:database", "${config:database}")
+	value, err := Get("mongo:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "mongodb://6.6.6.6/tsuru")
+	port, err := GetInt("mongo:database:port")
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 27017)
+}
+
+func (s *S) TestGetReferenceEnvironmentInReferencedKey(c *check.C) {
+	os.Unsetenv("DBHOST")
+	Set("database:host", "${DBHOST:-localhost}")
+	Set("database:url", "mongodb://${config:database:host}/tsuru")
+	value, err := Get("database:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "mongodb://localhost/tsuru")
+}
+
+func (s *S) TestGetReferenceNotFound(c *check.C) {
+	Set("database:url", "mongodb://${config:database:host}/tsuru")
+	_, err := Get("database:url")
+	c.Assert(err, check.FitsTypeOf, &ErrReference{})
+	c.Assert(err.Error(), check.Equals, `value for the key "database:url" references "database:host": key "database:host" not found`)
+	var notFound ErrKeyNotFound
+	c.Assert(errors.As(err, &notFound), check.Equals, true)
+	c.Assert(notFound.Key, check.Equals, "database:host")
+}
+
+func (s *S) TestGetReferenceCycle(c *check.C) {
+	Set("a", "${config:b}")
+	Set("b", "prefix-${config:c}")
+	Set("c", "${config:a}")
+	_, err := Get("a")
+	c.Assert(err, check.DeepEquals, &ErrReferenceCycle{Chain: []string{"a", "b", "c", "a"}})
+	c.Assert(err.Error(), check.Equals, "reference cycle: a -> b -> c -> a")
+	Set("self", "${config:self}")
+	_, err = Get("self")
+	c.Assert(err, check.DeepEquals, &ErrReferenceCycle{Chain: []string{"self", "self"}})
+}
+
+func (s *S) TestGetReferenceWithEnvExpansionDisabled(c *check.C) {
+	var conf Configuration
+	conf.Set("database:password", "s3cr3t")
+	conf.Set("leak", "${config:database:password}")
+	conf.SetEnvExpansion(false)
+	value, err := conf.Get("leak")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "${config:database:password}")
+}
+
+func (s *S) TestExplain(c *check.C) {
+	os.Unsetenv("DBHOST")
+	Set("database:host", "${DBHOST:-localhost}")
+	Set("database:url", "mongodb://${config:database:host}/tsuru")
+	explanation, err := Explain("database:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(explanation, check.Equals, `database:url: "mongodb://${config:database:host}/tsuru"
+  database:host: "${DBHOST:-localhost}"
+result: "mongodb://localhost/tsuru"
+`)
+}
+
+func (s *S) TestExplainCycle(c *check.C) {
+	Set("a", "${config:b}")
+	Set("b", "${config:a}")
+	explanation, err := Explain("a")
+	c.Assert(err, check.FitsTypeOf, &ErrReferenceCycle{})
+	c.Assert(explanation, check.Equals, `a: "${config:b}"
+  b: "${config:a}"
+`)
+}
+
+func (s *S) TestGetReferencePrefixWithOperator(c *check.C) {
+	var conf Configuration
+	conf.Set("a", "${config:-foo}")
+	conf.Set("b", "${file:?file is required}")
+	value, err := conf.Get("a")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "foo")
+	_, err = conf.Get("b")
+	c.Assert(err, check.DeepEquals, ErrRequiredEnv{Key: "b", Name: "file", Message: "file is required"})
+}
@@ -0,0 +1,284 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Type is the type of the value of a registered key.
+type Type int
+
+const (
+	// Any accepts values of any type.
+	Any Type = iota
+	String
+	Int
+	Uint
+	Float
+	Bool
+	Duration
+	List
+	Map
+)
+
+var typeNames = map[Type]string{
+	Any:      "any",
+	String:   "string",
+	Int:      "int",
+	Uint:     "uint",
+	Float:    "float",
+	Bool:     "bool",
+	Duration: "duration",
+	List:     "list",
+	Map:      "map",
+}
+
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// check reads the value of the key from the configuration using the getter of
+// the type, returning the error reported by the getter.
+func (t Type) check(c *Configuration, key string) error {
+	var err error
+	switch t {
+	case String:
+		_, err = c.GetString(key)
+	case Int:
+		_, err = c.GetInt(key)
+	case Uint:
+		_, err = c.GetUint(key)
+	case Float:
+		_, err = c.GetFloat(key)
+	case Bool:
+		_, err = c.GetBool(key)
+	case Duration:
+		_, err = c.GetDuration(key)
+	case List:
+		_, err = c.GetList(key)
+	case Map:
+		_, err = c.GetStringMap(key)
+	default:
+		_, err = c.Get(key)
+	}
+	return err
+}
+
+// Key describes a configuration key.
+type Key struct {
+	// Name is the full name of the key, in the format used by Get, for
+	// example: "database:url".
+	Name string
+
+	// Type is the type of the value. Values of other types are reported by
+	// the registry checker.
+	Type Type
+
+	// Default is the value used when the key is not defined. Nil means no
+	// default.
+	Default interface{}
+
+	// Required makes the registry checker fail when the key is not defined
+	// and has no default.
+	Required bool
+
+	// Doc describes the key.
+	Doc string
+
+	// Enum lists the values accepted by the key, if it's an enumeration. It's
+	// registered with RegisterEnum along with the default value, so GetEnum
+	// and CheckEnums use it too.
+	Enum Enum
+}
+
+// Registry holds the description of the keys used by an application. Packages
+// usually declare their keys in init functions, using Register.
+//
+// The zero value is an empty registry ready to use.
+type Registry struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+// DefaultRegistry is the registry used by Register and by typed handles like
+// StringKey.
+var DefaultRegistry Registry
+
+// Register adds the given key to DefaultRegistry and sets its default value,
+// if any, in DefaultConfig. It panics if the key is already registered.
+//
+//   func init() {
+//       config.Register(config.Key{
+//           Name:     "database:url",
+//           Type:     config.String,
+//           Default:  "127.0.0.1:27017",
+//           Required: true,
+//           Doc:      "address of the MongoDB server",
+//       })
+//   }
+func Register(key Key) {
+	DefaultRegistry.Register(key)
+	key.apply(&DefaultConfig)
+}
+
+// Register adds the given key to the registry. It panics if the key has no
+// name or is already registered. Use SetDefaults to apply default values to a
+// configuration.
+func (r *Registry) Register(key Key) {
+	if key.Name == "" {
+		panic("config: registering a key without name")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[key.Name]; ok {
+		panic(fmt.Sprintf("config: key %q registered twice", key.Name))
+	}
+	if r.keys == nil {
+		r.keys = make(map[string]Key)
+	}
+	r.keys[key.Name] = key
+}
+
+// Lookup returns the registered key with the given name.
+func (r *Registry) Lookup(name string) (Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[name]
+	return key, ok
+}
+
+// Keys returns all registered keys, sorted by name.
+func (r *Registry) Keys() []Key {
+	r.mu.RLock()
+	keys := make([]Key, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	r.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// Names returns the names of all registered keys, sorted, in the format
+// expected by UnknownKeys.
+func (r *Registry) Names() []string {
+	keys := r.Keys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.Name
+	}
+	return names
+}
+
+// SetDefaults sets the default values of the registered keys in the given
+// configuration, and registers their enums.
+func (r *Registry) SetDefaults(c *Configuration) {
+	for _, key := range r.Keys() {
+		key.apply(c)
+	}
+}
+
+// apply sets the default value and registers the enum of the key in the
+// given configuration.
+func (key Key) apply(c *Configuration) {
+	if key.Default != nil {
+		c.SetDefault(key.Name, key.Default)
+	}
+	if len(key.Enum.Values) > 0 {
+		c.RegisterEnum(key.Name, key.Enum)
+	}
+}
+
+// Check validates the given configuration against the registered keys. It
+// fails if any required key is not defined, or if any value doesn't match the
+// type, or the accepted values, of its key. All problems are reported in the
+// returned error.
+func (r *Registry) Check(c *Configuration) error {
+	var keyed []ValidationError
+	for _, key := range r.Keys() {
+		err := key.Type.check(c, key.Name)
+		if err == nil && len(key.Enum.Values) > 0 {
+			_, err = c.getEnum(key.Name, key.Enum)
+		}
+		if err == nil {
+			continue
+		}
+		if isMissing(err) {
+			if key.Required {
+				keyed = append(keyed, ValidationError{Key: key.Name, Message: fmt.Sprintf("key %q is required", key.Name)})
+			}
+			continue
+		}
+		keyed = append(keyed, ValidationError{Key: key.Name, Message: err.Error()})
+	}
+	if len(keyed) == 0 {
+		return nil
+	}
+	return &keyedErr{msg: joinMessages(keyed), keyed: keyed}
+}
+
+// CheckRegistry is a Checker that validates DefaultConfig against the keys in
+// DefaultRegistry, see Registry.Check.
+func CheckRegistry() error {
+	return DefaultRegistry.Check(&DefaultConfig)
+}
+
+// StringKey is a handle to a string key in DefaultConfig, for example:
+//
+//   url, err := config.StringKey("database:url").Get()
+type StringKey string
+
+func (k StringKey) Get() (string, error) {
+	return DefaultConfig.GetString(string(k))
+}
+
+// IntKey is a handle to an integer key in DefaultConfig.
+type IntKey string
+
+func (k IntKey) Get() (int, error) {
+	return DefaultConfig.GetInt(string(k))
+}
+
+// UintKey is a handle to an unsigned integer key in DefaultConfig.
+type UintKey string
+
+func (k UintKey) Get() (uint, error) {
+	return DefaultConfig.GetUint(string(k))
+}
+
+// FloatKey is a handle to a float key in DefaultConfig.
+type FloatKey string
+
+func (k FloatKey) Get() (float64, error) {
+	return DefaultConfig.GetFloat(string(k))
+}
+
+// BoolKey is a handle to a boolean key in DefaultConfig.
+type BoolKey string
+
+func (k BoolKey) Get() (bool, error) {
+	return DefaultConfig.GetBool(string(k))
+}
+
+// DurationKey is a handle to a duration key in DefaultConfig.
+type DurationKey string
+
+func (k DurationKey) Get() (time.Duration, error) {
+	return DefaultConfig.GetDuration(string(k))
+}
+
+// ListKey is a handle to a list key in DefaultConfig.
+type ListKey string
+
+func (k ListKey) Get() ([]string, error) {
+	return DefaultConfig.GetList(string(k))
+}
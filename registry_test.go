@@ -0,0 +1,146 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestRegistryRegister(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "database:url", Type: String, Doc: "address of the database"})
+	r.Register(Key{Name: "auth:token-expire-days", Type: Int, Default: 7})
+	key, ok := r.Lookup("database:url")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(key.Doc, check.Equals, "address of the database")
+	_, ok = r.Lookup("database:name")
+	c.Assert(ok, check.Equals, false)
+	keys := r.Keys()
+	c.Assert(keys, check.HasLen, 2)
+	c.Assert(keys[0].Name, check.Equals, "auth:token-expire-days")
+	c.Assert(keys[1].Name, check.Equals, "database:url")
+}
+
+func (s *S) TestRegistryRegisterTwice(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "database:url"})
+	c.Assert(func() { r.Register(Key{Name: "database:url"}) }, check.PanicMatches, `config: key "database:url" registered twice`)
+	c.Assert(func() { r.Register(Key{}) }, check.PanicMatches, `config: registering a key without name`)
+}
+
+func (s *S) TestRegistrySetDefaults(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "database:name", Type: String, Default: "tsuru"})
+	r.Register(Key{Name: "database:url", Type: String})
+	var conf Configuration
+	r.SetDefaults(&conf)
+	name, err := conf.GetString("database:name")
+	c.Assert(err, check.IsNil)
+	c.Assert(name, check.Equals, "tsuru")
+	_, err = conf.Get("database:url")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "database:url"})
+}
+
+func (s *S) TestRegistryCheck(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "database:url", Type: String, Required: true})
+	r.Register(Key{Name: "database:name", Type: String, Required: true, Default: "tsuru"})
+	r.Register(Key{Name: "debug", Type: Bool})
+	r.Register(Key{Name: "timeout", Type: Duration})
+	r.Register(Key{Name: "queue", Type: Map})
+	var conf Configuration
+	r.SetDefaults(&conf)
+	conf.Set("database:url", "127.0.0.1:27017")
+	conf.Set("debug", false)
+	c.Assert(r.Check(&conf), check.IsNil)
+	conf.Unset("database:url")
+	conf.Set("debug", "sometimes")
+	conf.Set("timeout", "forever")
+	err := r.Check(&conf)
+	c.Assert(err, check.ErrorMatches, `key "database:url" is required; `+
+		`value for the key "debug" is not a boolean; `+
+		`value for the key "timeout" is not a duration`)
+}
+
+func (s *S) TestRegistryCheckNullRequired(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "database:url", Type: String, Required: true})
+	var conf Configuration
+	conf.Set("database:url", nil)
+	c.Assert(r.Check(&conf), check.ErrorMatches, `key "database:url" is required`)
+}
+
+func (s *S) TestRegistryCheckOptionalUnsetUint(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "workers", Type: Uint})
+	var conf Configuration
+	conf.Set("debug", true)
+	c.Assert(r.Check(&conf), check.IsNil)
+	conf.Set("workers", -1)
+	c.Assert(r.Check(&conf), check.ErrorMatches, `value for the key "workers" is not a uint`)
+}
+
+func (s *S) TestRegistryCheckAnyType(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "pools"})
+	var conf Configuration
+	conf.Set("pools", []interface{}{"default"})
+	c.Assert(r.Check(&conf), check.IsNil)
+}
+
+func (s *S) TestCheckRegistry(c *check.C) {
+	Register(Key{Name: "database:url", Type: String, Required: true})
+	defer func() { DefaultRegistry = Registry{} }()
+	var buf bytes.Buffer
+	err := CheckWithWarnings([]Checker{CheckRegistry}, &buf)
+	c.Assert(err, check.ErrorMatches, `key "database:url" is required`)
+	Set("database:url", "127.0.0.1:27017")
+	c.Assert(CheckWithWarnings([]Checker{CheckRegistry}, &buf), check.IsNil)
+}
+
+func (s *S) TestTypeString(c *check.C) {
+	c.Assert(String.String(), check.Equals, "string")
+	c.Assert(Duration.String(), check.Equals, "duration")
+	c.Assert(Type(42).String(), check.Equals, "Type(42)")
+}
+
+func (s *S) TestKeyHandles(c *check.C) {
+	Register(Key{Name: "database:url", Type: String, Default: "127.0.0.1:27017"})
+	Register(Key{Name: "timeout", Type: Duration, Default: "10s"})
+	defer func() {
+		DefaultRegistry = Registry{}
+		DefaultConfig.defaults = nil
+	}()
+	Set("port", 8080)
+	Set("ratio", 0.5)
+	Set("debug", true)
+	Set("hosts", []interface{}{"a", "b"})
+	url, err := StringKey("database:url").Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(url, check.Equals, "127.0.0.1:27017")
+	timeout, err := DurationKey("timeout").Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(timeout, check.Equals, 10*time.Second)
+	port, err := IntKey("port").Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(port, check.Equals, 8080)
+	uport, err := UintKey("port").Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(uport, check.Equals, uint(8080))
+	ratio, err := FloatKey("ratio").Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(ratio, check.Equals, 0.5)
+	debug, err := BoolKey("debug").Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(debug, check.Equals, true)
+	hosts, err := ListKey("hosts").Get()
+	c.Assert(err, check.IsNil)
+	c.Assert(hosts, check.DeepEquals, []string{"a", "b"})
+	_, err = StringKey("unknown").Get()
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "unknown"})
+}
@@ -0,0 +1,195 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Severity tells whether a report entry is an error or a warning.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// ReportEntry is an error or warning found by a checker.
+type ReportEntry struct {
+	// Checker is the name of the function that found the problem, for
+	// example "config.CheckEnv".
+	Checker string `json:"checker"`
+
+	Severity Severity `json:"severity"`
+
+	// Key is the key related to the problem, when known.
+	Key string `json:"key,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (e ReportEntry) String() string {
+	msg := e.Message
+	if e.Key != "" && !strings.Contains(msg, fmt.Sprintf("%q", e.Key)) {
+		msg = e.Key + ": " + msg
+	}
+	return fmt.Sprintf("%s: %s: %s", strings.ToUpper(string(e.Severity)), e.Checker, msg)
+}
+
+// Report holds every error and warning found by RunCheckers.
+type Report struct {
+	Entries []ReportEntry `json:"entries"`
+}
+
+// Errors returns the entries with SeverityError.
+func (r *Report) Errors() []ReportEntry {
+	return r.filter(SeverityError)
+}
+
+// Warnings returns the entries with SeverityWarning.
+func (r *Report) Warnings() []ReportEntry {
+	return r.filter(SeverityWarning)
+}
+
+func (r *Report) filter(severity Severity) []ReportEntry {
+	var entries []ReportEntry
+	for _, e := range r.Entries {
+		if e.Severity == severity {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// HasErrors reports whether any checker found an error.
+func (r *Report) HasErrors() bool {
+	return len(r.Errors()) > 0
+}
+
+// WriteText writes the report to w, one entry per line, for example:
+//
+//   ERROR: config.CheckEnv: undefined environment variables: DBHOST (used by database:host)
+//   WARNING: config.CheckDeprecated: key "debug" is deprecated
+func (r *Report) WriteText(w io.Writer) error {
+	for _, e := range r.Entries {
+		if _, err := fmt.Fprintln(w, e.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSON returns the report encoded as JSON.
+func (r *Report) JSON() ([]byte, error) {
+	entries := r.Entries
+	if entries == nil {
+		entries = []ReportEntry{}
+	}
+	return json.Marshal(Report{Entries: entries})
+}
+
+// RunCheckers runs every checker and returns a report with all the errors and
+// warnings found, unlike Check and CheckWithWarnings, which stop at the first
+// error.
+//
+// Errors that list several problems, like the ones returned by ValidateSchema,
+// Validate, CheckUnknownKeys or CheckEnv, are split in one entry per problem,
+// keyed by the problem key.
+func RunCheckers(checkers []Checker) *Report {
+	report := &Report{}
+	for _, check := range checkers {
+		err := check()
+		if err == nil {
+			continue
+		}
+		name := checkerName(check)
+		for _, e := range reportEntries(err) {
+			e.Checker = name
+			report.Entries = append(report.Entries, e)
+		}
+	}
+	return report
+}
+
+func reportEntries(err error) []ReportEntry {
+	switch e := err.(type) {
+	case *warningErr:
+		if len(e.keyed) == 0 {
+			return []ReportEntry{{Severity: SeverityWarning, Message: e.msg}}
+		}
+		return keyedEntries(SeverityWarning, e.keyed)
+	case *keyedErr:
+		return keyedEntries(SeverityError, e.keyed)
+	case *ErrSchema:
+		entries := make([]ReportEntry, len(e.Violations))
+		for i, v := range e.Violations {
+			entries[i] = ReportEntry{Severity: SeverityError, Key: v.Key, Message: v.Message}
+		}
+		return entries
+	case *ErrValidation:
+		var entries []ReportEntry
+		for _, v := range e.Errors {
+			entries = append(entries, ReportEntry{Severity: SeverityError, Key: v.Key, Message: v.Message})
+		}
+		for _, v := range e.Warnings {
+			entries = append(entries, ReportEntry{Severity: SeverityWarning, Key: v.Key, Message: v.Message})
+		}
+		return entries
+	}
+	return []ReportEntry{{Severity: SeverityError, Key: errorKey(err), Message: err.Error()}}
+}
+
+func keyedEntries(severity Severity, keyed []ValidationError) []ReportEntry {
+	entries := make([]ReportEntry, len(keyed))
+	for i, v := range keyed {
+		entries[i] = ReportEntry{Severity: severity, Key: v.Key, Message: v.Message}
+	}
+	return entries
+}
+
+// errorKey returns the key related to the given error, if known.
+func errorKey(err error) string {
+	switch e := err.(type) {
+	case ErrKeyNotFound:
+		return e.Key
+	case ErrNullValue:
+		return e.Key
+	case *InvalidValue:
+		return e.key
+	case ErrRequiredEnv:
+		return e.Key
+	case ErrUndefinedEnv:
+		return e.Key
+	case *ErrReference:
+		return e.Key
+	case *ErrDecrypt:
+		return e.Key
+	}
+	return ""
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// checkerName returns the name of the function implementing the checker,
+// without the package path. Checkers returned by functions, like
+// CheckSchema, are named after them.
+func checkerName(check Checker) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(check).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	return closureSuffix.ReplaceAllString(name, "")
+}
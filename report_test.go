@@ -0,0 +1,177 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"gopkg.in/check.v1"
+)
+
+func failingChecker() error {
+	return errors.New("something is wrong")
+}
+
+func warningChecker() error {
+	return NewWarning("something looks odd")
+}
+
+func (s *S) TestRunCheckers(c *check.C) {
+	var calls int
+	counter := func() error {
+		calls++
+		return nil
+	}
+	report := RunCheckers([]Checker{failingChecker, counter, warningChecker, failingChecker})
+	c.Assert(calls, check.Equals, 1)
+	c.Assert(report.Entries, check.DeepEquals, []ReportEntry{
+		{Checker: "config.failingChecker", Severity: SeverityError, Message: "something is wrong"},
+		{Checker: "config.warningChecker", Severity: SeverityWarning, Message: "something looks odd"},
+		{Checker: "config.failingChecker", Severity: SeverityError, Message: "something is wrong"},
+	})
+	c.Assert(report.HasErrors(), check.Equals, true)
+	c.Assert(report.Errors(), check.HasLen, 2)
+	c.Assert(report.Warnings(), check.HasLen, 1)
+}
+
+func (s *S) TestRunCheckersNoProblems(c *check.C) {
+	report := RunCheckers([]Checker{func() error { return nil }})
+	c.Assert(report.Entries, check.HasLen, 0)
+	c.Assert(report.HasErrors(), check.Equals, false)
+	data, err := report.JSON()
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, `{"entries":[]}`)
+}
+
+func (s *S) TestRunCheckersKeys(c *check.C) {
+	var conf Configuration
+	err := conf.ReadConfigBytes([]byte(`
+database:
+  url: mongodb://localhost:27017
+  port: 80
+workers: 2
+debug: true
+`))
+	c.Assert(err, check.IsNil)
+	report := RunCheckers([]Checker{
+		conf.CheckStruct("", validatedConfig{}),
+		func() error {
+			_, err := conf.GetInt("database:url")
+			return err
+		},
+	})
+	c.Assert(report.Entries, check.DeepEquals, []ReportEntry{
+		{Checker: "config.(*Configuration).CheckStruct", Severity: SeverityWarning, Key: "database:port", Message: "must be at least 1024"},
+		{Checker: "config.(*Configuration).CheckStruct", Severity: SeverityWarning, Key: "debug", Message: "must be one of: false"},
+		{Checker: "config.(*S).TestRunCheckersKeys", Severity: SeverityError, Key: "database:url", Message: `value for the key "database:url" is not a int`},
+	})
+}
+
+func (s *S) TestRunCheckersSchema(c *check.C) {
+	var conf Configuration
+	err := conf.ReadConfigBytes([]byte("port: high\n"))
+	c.Assert(err, check.IsNil)
+	schema := []byte(`{"type": "object", "properties": {"port": {"type": "integer"}}, "required": ["host"]}`)
+	report := RunCheckers([]Checker{conf.CheckSchema(schema)})
+	c.Assert(report.Entries, check.HasLen, 2)
+	for _, e := range report.Entries {
+		c.Check(e.Checker, check.Equals, "config.(*Configuration).CheckSchema")
+		c.Check(e.Severity, check.Equals, SeverityError)
+	}
+	c.Assert(report.Entries[0].Key, check.Equals, "")
+	c.Assert(report.Entries[1].Key, check.Equals, "port")
+}
+
+func (s *S) TestRunCheckersSplitsKeyedProblems(c *check.C) {
+	os.Unsetenv("NOPE_A")
+	os.Unsetenv("NOPE_B")
+	var conf Configuration
+	err := conf.ReadConfigBytes([]byte(`
+databse: x
+old: 1
+older: 2
+mode: fast
+port: high
+a: $NOPE_A
+b: $NOPE_B
+`))
+	c.Assert(err, check.IsNil)
+	conf.Alias("old", "new")
+	conf.Alias("older", "newer")
+	conf.RegisterEnum("mode", Enum{Values: []string{"slow"}})
+	var r Registry
+	r.Register(Key{Name: "port", Type: Int})
+	r.Register(Key{Name: "host", Type: String, Required: true})
+	report := RunCheckers([]Checker{
+		conf.CheckUnknownKeys([]string{"database", "host", "new", "newer", "mode", "port", "a", "b"}),
+		conf.CheckDeprecated,
+		conf.CheckEnums,
+		func() error { return r.Check(&conf) },
+		conf.CheckEnv,
+	})
+	entries := make([][2]string, len(report.Entries))
+	for i, e := range report.Entries {
+		entries[i] = [2]string{e.Key, e.Message}
+	}
+	c.Assert(entries, check.DeepEquals, [][2]string{
+		{"databse", `unknown key "databse" (did you mean "database"?)`},
+		{"old", `unknown key "old"`},
+		{"older", `unknown key "older"`},
+		{"old", `key "old" is deprecated, use "new" instead`},
+		{"older", `key "older" is deprecated, use "newer" instead`},
+		{"mode", `value for the key "mode" is not a valid value (one of: slow)`},
+		{"host", `key "host" is required`},
+		{"port", `value for the key "port" is not a int`},
+		{"a", "undefined environment variable NOPE_A"},
+		{"b", "undefined environment variable NOPE_B"},
+	})
+	err = conf.CheckEnv()
+	c.Assert(err, check.ErrorMatches, `undefined environment variables: NOPE_A \(used by a\); NOPE_B \(used by b\)`)
+	err = r.Check(&conf)
+	c.Assert(err, check.ErrorMatches, `key "host" is required; value for the key "port" is not a int`)
+}
+
+func (s *S) TestReportWriteText(c *check.C) {
+	report := Report{Entries: []ReportEntry{
+		{Checker: "config.CheckEnv", Severity: SeverityError, Message: "undefined environment variables"},
+		{Checker: "config.CheckStruct", Severity: SeverityWarning, Key: "debug", Message: "must be one of: false"},
+		{Checker: "config.CheckEnums", Severity: SeverityError, Key: "mode", Message: `value for the key "mode" is not a valid value`},
+	}}
+	var buf bytes.Buffer
+	err := report.WriteText(&buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, `ERROR: config.CheckEnv: undefined environment variables
+WARNING: config.CheckStruct: debug: must be one of: false
+ERROR: config.CheckEnums: value for the key "mode" is not a valid value
+`)
+}
+
+func (s *S) TestReportJSON(c *check.C) {
+	report := Report{Entries: []ReportEntry{
+		{Checker: "config.CheckEnv", Severity: SeverityError, Message: "undefined environment variables"},
+		{Checker: "config.CheckStruct", Severity: SeverityWarning, Key: "debug", Message: "must be one of: false"},
+	}}
+	data, err := report.JSON()
+	c.Assert(err, check.IsNil)
+	var decoded Report
+	err = json.Unmarshal(data, &decoded)
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded, check.DeepEquals, report)
+	c.Assert(string(data), check.Matches, `.*"severity":"warning","key":"debug".*`)
+}
+
+func (s *S) TestCheckStillStopsAtFirstError(c *check.C) {
+	var calls int
+	counter := func() error {
+		calls++
+		return nil
+	}
+	err := Check([]Checker{failingChecker, counter})
+	c.Assert(err, check.ErrorMatches, "something is wrong")
+	c.Assert(calls, check.Equals, 0)
+}
@@ -0,0 +1,258 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const schemaURL = "config.schema.json"
+
+// SchemaViolation describes a value that doesn't match the schema.
+type SchemaViolation struct {
+	// Key is the key holding the invalid value, in the format used by Get.
+	// Items of lists are identified by their index, for example
+	// "pools[2]:name". It's empty for violations in the root of the
+	// configuration.
+	Key string
+
+	// Message describes the violation.
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	if v.Key == "" {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", v.Key, v.Message)
+}
+
+// ErrSchema is returned by ValidateSchema when the configuration doesn't
+// match the schema. It lists every violation found.
+type ErrSchema struct {
+	Violations []SchemaViolation
+}
+
+func (e *ErrSchema) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("configuration doesn't match the schema: %s", strings.Join(msgs, "; "))
+}
+
+func compileSchema(schema []byte) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(schema)); err != nil {
+		return nil, fmt.Errorf("invalid schema: %s", err)
+	}
+	s, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %s", err)
+	}
+	return s, nil
+}
+
+// ValidateSchema validates the configuration against the given JSON Schema.
+// Values are expanded like Get does before the validation, and integers,
+// floats, booleans and strings are validated with their YAML types.
+//
+// When the configuration doesn't match the schema, the returned error is an
+// *ErrSchema listing every violation, identified by its key.
+func ValidateSchema(schema []byte) error {
+	return DefaultConfig.ValidateSchema(schema)
+}
+
+func (c *Configuration) ValidateSchema(schema []byte) error {
+	s, err := compileSchema(schema)
+	if err != nil {
+		return err
+	}
+	return c.validateSchema(s)
+}
+
+func (c *Configuration) validateSchema(s *jsonschema.Schema) error {
+	// Expanded strings are typed as if they were written in the file, so
+	// "port: $PORT" is validated as a number.
+	tree, err := c.expandValue("", c.Data(), false, anyType)
+	if err != nil {
+		return err
+	}
+	value := toJSONValue(tree)
+	if value == nil {
+		value = map[string]interface{}{}
+	}
+	err = s.Validate(value)
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+	var violations []SchemaViolation
+	var collect func(*jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			violations = append(violations, SchemaViolation{
+				Key:     pointerKey(value, e.InstanceLocation),
+				Message: e.Message,
+			})
+		}
+		for _, cause := range e.Causes {
+			collect(cause)
+		}
+	}
+	collect(ve)
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Key != violations[j].Key {
+			return violations[i].Key < violations[j].Key
+		}
+		return violations[i].Message < violations[j].Message
+	})
+	return &ErrSchema{Violations: violations}
+}
+
+// CheckSchema returns a Checker that validates the configuration against the
+// given JSON Schema, see ValidateSchema.
+func CheckSchema(schema []byte) Checker {
+	return DefaultConfig.CheckSchema(schema)
+}
+
+func (c *Configuration) CheckSchema(schema []byte) Checker {
+	return func() error {
+		return c.ValidateSchema(schema)
+	}
+}
+
+// SetReloadSchema makes ReadAndWatchConfigFile validate reloaded files
+// against the given JSON Schema before using them. Files that don't match the
+// schema are rejected: the configuration is kept unchanged and the error is
+// reported through ReloadErrors. A nil schema disables the validation.
+func SetReloadSchema(schema []byte) error {
+	return DefaultConfig.SetReloadSchema(schema)
+}
+
+func (c *Configuration) SetReloadSchema(schema []byte) error {
+	var s *jsonschema.Schema
+	if schema != nil {
+		var err error
+		if s, err = compileSchema(schema); err != nil {
+			return err
+		}
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.reloadSchema = s
+	return nil
+}
+
+// reloadConfigFile reads the configuration file again, validating it against
+// the reload schema, if any, before storing it.
+func (c *Configuration) reloadConfigFile(filePath string) error {
+	c.RLock()
+	s := c.reloadSchema
+	c.RUnlock()
+	if s == nil {
+		return c.ReadConfigFile(filePath)
+	}
+	data, _, err := c.readMigrated(filePath)
+	if err != nil {
+		return err
+	}
+	if err = c.withData(data).validateSchema(s); err != nil {
+		return err
+	}
+	c.Store(data)
+	return nil
+}
+
+// withData returns a configuration holding the given data, with the same
+// settings of c, so values read from it are expanded the same way. Settings
+// held in maps and slices are copied, as c may change them while the copy is
+// read.
+func (c *Configuration) withData(data map[interface{}]interface{}) *Configuration {
+	c.RLock()
+	defer c.RUnlock()
+	tmp := &Configuration{
+		strictEnv:   c.strictEnv,
+		envDisabled: c.envDisabled,
+		envAllow:    copySet(c.envAllow),
+		envPrefixes: append([]string(nil), c.envPrefixes...),
+		refAllow:    copySet(c.refAllow),
+		rawKeys:     copySet(c.rawKeys),
+		keys:        c.keys,
+		coercion:    c.coercion,
+		defaults:    c.defaults,
+	}
+	if c.aliases != nil {
+		tmp.aliases = copyRefs(c.aliases)
+	}
+	tmp.store(data)
+	return tmp
+}
+
+func copySet(set map[string]bool) map[string]bool {
+	if set == nil {
+		return nil
+	}
+	result := make(map[string]bool, len(set))
+	for k, v := range set {
+		result[k] = v
+	}
+	return result
+}
+
+// toJSONValue converts a configuration tree to the types used by
+// encoding/json, like toStrMap does for maps.
+func toJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			result[fmt.Sprint(k)] = toJSONValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = toJSONValue(item)
+		}
+		return result
+	case nil, bool, string, int, int64, uint64, float32, float64:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// pointerKey converts a JSON pointer in the given value to a key, in the
+// format used by Get.
+func pointerKey(value interface{}, pointer string) string {
+	if pointer == "" {
+		return ""
+	}
+	var key string
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch v := value.(type) {
+		case []interface{}:
+			if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(v) {
+				key = itemKey(key, i)
+				value = v[i]
+				continue
+			}
+		case map[string]interface{}:
+			value = v[token]
+		}
+		key = joinKey(key, token)
+	}
+	return key
+}
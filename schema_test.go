@@ -0,0 +1,204 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+var testSchema = []byte(`{
+  "type": "object",
+  "required": ["database"],
+  "properties": {
+    "database": {
+      "type": "object",
+      "required": ["url"],
+      "properties": {
+        "url": {"type": "string"},
+        "port": {"type": "integer", "minimum": 1, "maximum": 65535}
+      }
+    },
+    "debug": {"type": "boolean"},
+    "provisioner": {"enum": ["docker", "kubernetes"]},
+    "pools": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {"name": {"type": "string"}}
+      }
+    }
+  }
+}`)
+
+func (s *S) TestValidateSchema(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+database:
+  url: 127.0.0.1:27017
+  port: 27017
+debug: true
+provisioner: docker
+pools:
+  - name: default
+`))
+	c.Assert(err, check.IsNil)
+	c.Assert(ValidateSchema(testSchema), check.IsNil)
+}
+
+func (s *S) TestValidateSchemaViolations(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+database:
+  port: 70000
+debug: "yes"
+provisioner: swarm
+pools:
+  - name: default
+  - public: true
+`))
+	c.Assert(err, check.IsNil)
+	err = ValidateSchema(testSchema)
+	c.Assert(err, check.FitsTypeOf, &ErrSchema{})
+	violations := err.(*ErrSchema).Violations
+	keys := make([]string, len(violations))
+	for i, v := range violations {
+		keys[i] = v.Key
+	}
+	c.Assert(keys, check.DeepEquals, []string{"database", "database:port", "debug", "pools[1]", "provisioner"})
+	c.Assert(violations[0].Message, check.Matches, `missing properties: .*url.*`)
+	c.Assert(violations[1].Message, check.Matches, `must be <= 65535 but found 70000`)
+	c.Assert(err, check.ErrorMatches, `configuration doesn't match the schema: database: missing properties: .*; database:port: .*; debug: .*; pools\[1\]: .*; provisioner: .*`)
+}
+
+func (s *S) TestValidateSchemaRoot(c *check.C) {
+	var conf Configuration
+	err := conf.ValidateSchema(testSchema)
+	c.Assert(err, check.FitsTypeOf, &ErrSchema{})
+	violations := err.(*ErrSchema).Violations
+	c.Assert(violations, check.HasLen, 1)
+	c.Assert(violations[0].Key, check.Equals, "")
+	c.Assert(violations[0].String(), check.Matches, `missing properties: .*database.*`)
+}
+
+func (s *S) TestValidateSchemaExpandsValues(c *check.C) {
+	os.Setenv("DBPORT", "27017")
+	defer os.Unsetenv("DBPORT")
+	var conf Configuration
+	conf.Set("database:url", "127.0.0.1:${DBPORT}")
+	conf.Set("database:port", "$DBPORT")
+	conf.Set("debug", true)
+	c.Assert(conf.ValidateSchema(testSchema), check.IsNil)
+}
+
+func (s *S) TestValidateSchemaInvalidSchema(c *check.C) {
+	var conf Configuration
+	err := conf.ValidateSchema([]byte(`{"type": `))
+	c.Assert(err, check.ErrorMatches, `invalid schema: .*`)
+	err = conf.ValidateSchema([]byte(`{"type": 42}`))
+	c.Assert(err, check.ErrorMatches, `invalid schema: .*`)
+}
+
+func (s *S) TestCheckSchema(c *check.C) {
+	Set("database:port", 8080)
+	err := Check([]Checker{CheckSchema(testSchema)})
+	c.Assert(err, check.FitsTypeOf, &ErrSchema{})
+	Set("database:url", "127.0.0.1:27017")
+	c.Assert(Check([]Checker{CheckSchema(testSchema)}), check.IsNil)
+}
+
+func (s *S) TestToJSONValue(c *check.C) {
+	t := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	value := toJSONValue(map[interface{}]interface{}{
+		"a": []interface{}{1, map[interface{}]interface{}{2: "b"}},
+		"t": t,
+		"d": time.Second,
+	})
+	c.Assert(value, check.DeepEquals, map[string]interface{}{
+		"a": []interface{}{1, map[string]interface{}{"2": "b"}},
+		"t": "2020-01-02T03:04:05Z",
+		"d": "1s",
+	})
+}
+
+func (s *S) TestPointerKey(c *check.C) {
+	value := map[string]interface{}{
+		"pools": []interface{}{map[string]interface{}{"name": "a"}},
+		"a/b":   map[string]interface{}{"0": 1},
+	}
+	c.Assert(pointerKey(value, ""), check.Equals, "")
+	c.Assert(pointerKey(value, "/pools/0/name"), check.Equals, "pools[0]:name")
+	c.Assert(pointerKey(value, "/a~1b/0"), check.Equals, "a/b:0")
+}
+
+func (s *S) TestSetReloadSchema(c *check.C) {
+	var conf Configuration
+	err := conf.SetReloadSchema([]byte(`{`))
+	c.Assert(err, check.ErrorMatches, `invalid schema: .*`)
+	c.Assert(conf.SetReloadSchema(testSchema), check.IsNil)
+	c.Assert(conf.reloadSchema, check.NotNil)
+	c.Assert(conf.SetReloadSchema(nil), check.IsNil)
+	c.Assert(conf.reloadSchema, check.IsNil)
+}
+
+func (s *S) TestReadAndWatchConfigFileRejectsInvalidReload(c *check.C) {
+	path := filepath.Join(c.MkDir(), "tsuru.conf")
+	err := ioutil.WriteFile(path, []byte("database:\n  url: 127.0.0.1:27017\n"), 0644)
+	c.Assert(err, check.IsNil)
+	var conf Configuration
+	err = conf.SetReloadSchema(testSchema)
+	c.Assert(err, check.IsNil)
+	errs := conf.ReloadErrors()
+	err = conf.ReadAndWatchConfigFile(path)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(path, []byte("database:\n  port: 0\n"), 0644)
+	c.Assert(err, check.IsNil)
+	select {
+	case err = <-errs:
+		c.Assert(err, check.FitsTypeOf, &ErrSchema{})
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting for the reload error")
+	}
+	url, err := conf.GetString("database:url")
+	c.Assert(err, check.IsNil)
+	c.Assert(url, check.Equals, "127.0.0.1:27017")
+	err = ioutil.WriteFile(path, []byte("database:\n  url: db.example.com\n"), 0644)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 100; i++ {
+		if url, _ = conf.GetString("database:url"); url == "db.example.com" {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	c.Assert(url, check.Equals, "db.example.com")
+}
+
+func (s *S) TestWithDataCopiesSettings(c *check.C) {
+	var conf Configuration
+	conf.AllowEnv("HOME")
+	conf.SetRaw("raw")
+	conf.Alias("old", "new")
+	tmp := conf.withData(map[interface{}]interface{}{"old": "$HOME", "raw": "$HOME"})
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			conf.AllowEnv(fmt.Sprintf("VAR%d", i))
+			conf.SetRaw(fmt.Sprintf("raw%d", i))
+			conf.Alias(fmt.Sprintf("old%d", i), "new")
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		_, err := tmp.Get("new")
+		c.Assert(err, check.IsNil)
+	}
+	<-done
+	c.Assert(tmp.envAllow, check.DeepEquals, map[string]bool{"HOME": true})
+	c.Assert(tmp.aliases, check.DeepEquals, map[string][]string{"new": {"old"}})
+}
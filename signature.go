@@ -0,0 +1,90 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// ErrInvalidSignature is returned by ReadConfigFile, when trusted keys are
+// set, if the configuration file is not signed or its signature doesn't match
+// any of the trusted keys.
+type ErrInvalidSignature struct {
+	Path   string
+	Reason string
+}
+
+func (e *ErrInvalidSignature) Error() string {
+	return fmt.Sprintf("invalid signature for %s: %s", e.Path, e.Reason)
+}
+
+// SetTrustedKeys defines the public keys used to verify the signature of
+// configuration files. Once set, ReadConfigFile and ReadAndWatchConfigFile
+// refuse files that don't have a detached ed25519 signature, in a file with
+// the same path plus the ".sig" extension, made by one of the given keys.
+//
+// Signature files contain either the raw signature or its base64 encoding,
+// see SignConfigFile. Calling SetTrustedKeys without keys disables the
+// verification.
+func SetTrustedKeys(keys ...ed25519.PublicKey) {
+	DefaultConfig.SetTrustedKeys(keys...)
+}
+
+func (c *Configuration) SetTrustedKeys(keys ...ed25519.PublicKey) {
+	c.Lock()
+	defer c.Unlock()
+	c.trustedKeys = keys
+}
+
+// SignConfigFile signs the configuration file in the given path, writing the
+// detached signature, base64 encoded, to the path plus the ".sig" extension.
+func SignConfigFile(filePath string, key ed25519.PrivateKey, perm os.FileMode) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	sig := ed25519.Sign(key, data)
+	return ioutil.WriteFile(signaturePath(filePath), []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), perm)
+}
+
+func signaturePath(filePath string) string {
+	return filePath + ".sig"
+}
+
+// verifySignature checks the signature of the data read from the given path
+// against the trusted keys, if there are any.
+func (c *Configuration) verifySignature(filePath string, data []byte) error {
+	c.RLock()
+	keys := c.trustedKeys
+	c.RUnlock()
+	if len(keys) == 0 {
+		return nil
+	}
+	sig, err := ioutil.ReadFile(signaturePath(filePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ErrInvalidSignature{Path: filePath, Reason: "file is not signed"}
+		}
+		return err
+	}
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sig)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return &ErrInvalidSignature{Path: filePath, Reason: "malformed signature"}
+		}
+		sig = decoded
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, data, sig) {
+			return nil
+		}
+	}
+	return &ErrInvalidSignature{Path: filePath, Reason: "signature doesn't match any trusted key"}
+}
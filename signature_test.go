@@ -0,0 +1,143 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func newSigningKey(c *check.C) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	c.Assert(err, check.IsNil)
+	return pub, priv
+}
+
+func (s *S) TestReadConfigFileSigned(c *check.C) {
+	pub, priv := newSigningKey(c)
+	path := writeSecret(c, c.MkDir(), "tsuru.conf", "registry: registry.example.com\n")
+	err := SignConfigFile(path, priv, 0600)
+	c.Assert(err, check.IsNil)
+	var conf Configuration
+	conf.SetTrustedKeys(pub)
+	err = conf.ReadConfigFile(path)
+	c.Assert(err, check.IsNil)
+	value, err := conf.GetString("registry")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "registry.example.com")
+}
+
+func (s *S) TestReadConfigFileRawSignature(c *check.C) {
+	pub, priv := newSigningKey(c)
+	otherPub, _ := newSigningKey(c)
+	path := writeSecret(c, c.MkDir(), "tsuru.conf", "registry: registry.example.com\n")
+	data, err := ioutil.ReadFile(path)
+	c.Assert(err, check.IsNil)
+	err = ioutil.WriteFile(path+".sig", ed25519.Sign(priv, data), 0600)
+	c.Assert(err, check.IsNil)
+	var conf Configuration
+	conf.SetTrustedKeys(otherPub, pub)
+	err = conf.ReadConfigFile(path)
+	c.Assert(err, check.IsNil)
+}
+
+func (s *S) TestReadConfigFileUnsigned(c *check.C) {
+	pub, _ := newSigningKey(c)
+	path := writeSecret(c, c.MkDir(), "tsuru.conf", "registry: registry.example.com\n")
+	var conf Configuration
+	conf.SetTrustedKeys(pub)
+	err := conf.ReadConfigFile(path)
+	c.Assert(err, check.DeepEquals, &ErrInvalidSignature{Path: path, Reason: "file is not signed"})
+	c.Assert(conf.Data(), check.IsNil)
+}
+
+func (s *S) TestReadConfigFileTampered(c *check.C) {
+	pub, priv := newSigningKey(c)
+	dir := c.MkDir()
+	path := writeSecret(c, dir, "tsuru.conf", "registry: registry.example.com\n")
+	err := SignConfigFile(path, priv, 0600)
+	c.Assert(err, check.IsNil)
+	writeSecret(c, dir, "tsuru.conf", "registry: evil.example.com\n")
+	var conf Configuration
+	conf.SetTrustedKeys(pub)
+	err = conf.ReadConfigFile(path)
+	c.Assert(err, check.DeepEquals, &ErrInvalidSignature{Path: path, Reason: "signature doesn't match any trusted key"})
+	c.Assert(err.Error(), check.Equals, "invalid signature for "+path+": signature doesn't match any trusted key")
+	c.Assert(conf.Data(), check.IsNil)
+}
+
+func (s *S) TestReadConfigFileUntrustedKey(c *check.C) {
+	pub, _ := newSigningKey(c)
+	_, otherPriv := newSigningKey(c)
+	path := writeSecret(c, c.MkDir(), "tsuru.conf", "registry: registry.example.com\n")
+	err := SignConfigFile(path, otherPriv, 0600)
+	c.Assert(err, check.IsNil)
+	var conf Configuration
+	conf.SetTrustedKeys(pub)
+	err = conf.ReadConfigFile(path)
+	c.Assert(err, check.FitsTypeOf, &ErrInvalidSignature{})
+}
+
+func (s *S) TestReadConfigFileMalformedSignature(c *check.C) {
+	pub, _ := newSigningKey(c)
+	dir := c.MkDir()
+	path := writeSecret(c, dir, "tsuru.conf", "registry: registry.example.com\n")
+	writeSecret(c, dir, "tsuru.conf.sig", "not a signature")
+	var conf Configuration
+	conf.SetTrustedKeys(pub)
+	err := conf.ReadConfigFile(path)
+	c.Assert(err, check.DeepEquals, &ErrInvalidSignature{Path: path, Reason: "malformed signature"})
+}
+
+func (s *S) TestWatchConfigFileRejectsTamperedReload(c *check.C) {
+	pub, priv := newSigningKey(c)
+	dir := c.MkDir()
+	path := writeSecret(c, dir, "tsuru.conf", "registry: registry.example.com\n")
+	err := SignConfigFile(path, priv, 0600)
+	c.Assert(err, check.IsNil)
+	var conf Configuration
+	conf.SetTrustedKeys(pub)
+	errs := conf.ReloadErrors()
+	err = conf.ReadAndWatchConfigFile(path)
+	c.Assert(err, check.IsNil)
+	writeSecret(c, dir, "tsuru.conf", "registry: evil.example.com\n")
+	select {
+	case err = <-errs:
+		c.Assert(err, check.FitsTypeOf, &ErrInvalidSignature{})
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting for the reload error")
+	}
+	value, err := conf.GetString("registry")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "registry.example.com")
+	err = SignConfigFile(path, priv, 0600)
+	c.Assert(err, check.IsNil)
+	time.Sleep(1e8)
+	value, err = conf.GetString("registry")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "evil.example.com")
+}
+
+func (s *S) TestWatchConfigFileReportsInvalidYaml(c *check.C) {
+	dir := c.MkDir()
+	path := writeSecret(c, dir, "tsuru.conf", "registry: registry.example.com\n")
+	var conf Configuration
+	err := conf.ReadAndWatchConfigFile(path)
+	c.Assert(err, check.IsNil)
+	writeSecret(c, dir, "tsuru.conf", "registry: [\n")
+	select {
+	case err = <-conf.ReloadErrors():
+		c.Assert(err, check.NotNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("timed out waiting for the reload error")
+	}
+	value, err := conf.GetString("registry")
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "registry.example.com")
+}
@@ -0,0 +1,205 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	urlFormat      = "URL (e.g. https://example.com/path)"
+	ipFormat       = "valid IP address (e.g. 192.168.0.1 or ::1)"
+	cidrFormat     = "CIDR (e.g. 10.0.0.0/8)"
+	hostPortFormat = "host:port (e.g. localhost:8080)"
+	regexpFormat   = "regular expression (RE2 syntax)"
+	fileModeFormat = `quoted file mode (e.g. "0644")`
+	timeFormat     = "time (RFC3339, e.g. 2006-01-02T15:04:05Z)"
+)
+
+// GetURL parses and returns an absolute URL from the config file, for
+// example: https://tsuru.example.com/api.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetURL(key string) (*url.URL, error) {
+	return DefaultConfig.GetURL(key)
+}
+
+func (c *Configuration) GetURL(key string) (*url.URL, error) {
+	value, err := c.stringValue(key, urlFormat)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return nil, &InvalidValue{key, urlFormat}
+	}
+	return u, nil
+}
+
+// GetIP parses and returns an IPv4 or IPv6 address from the config file.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetIP(key string) (net.IP, error) {
+	return DefaultConfig.GetIP(key)
+}
+
+func (c *Configuration) GetIP(key string) (net.IP, error) {
+	value, err := c.stringValue(key, ipFormat)
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, &InvalidValue{key, ipFormat}
+	}
+	return ip, nil
+}
+
+// GetIPNet parses and returns a network, in CIDR notation, from the config
+// file, for example: 10.0.0.0/8.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetIPNet(key string) (*net.IPNet, error) {
+	return DefaultConfig.GetIPNet(key)
+}
+
+func (c *Configuration) GetIPNet(key string) (*net.IPNet, error) {
+	value, err := c.stringValue(key, cidrFormat)
+	if err != nil {
+		return nil, err
+	}
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, &InvalidValue{key, cidrFormat}
+	}
+	return ipNet, nil
+}
+
+// GetHostPort parses an address in the format host:port from the config file,
+// returning the host and the port. IPv6 hosts must be enclosed in brackets,
+// for example: [::1]:8080.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetHostPort(key string) (string, int, error) {
+	return DefaultConfig.GetHostPort(key)
+}
+
+func (c *Configuration) GetHostPort(key string) (string, int, error) {
+	value, err := c.stringValue(key, hostPortFormat)
+	if err != nil {
+		return "", 0, err
+	}
+	host, portStr, err := net.SplitHostPort(value)
+	if err == nil {
+		var port uint64
+		if port, err = strconv.ParseUint(portStr, 10, 16); err == nil {
+			return host, int(port), nil
+		}
+	}
+	return "", 0, &InvalidValue{key, hostPortFormat}
+}
+
+// GetRegexp compiles and returns a regular expression, in RE2 syntax, from the
+// config file.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetRegexp(key string) (*regexp.Regexp, error) {
+	return DefaultConfig.GetRegexp(key)
+}
+
+func (c *Configuration) GetRegexp(key string) (*regexp.Regexp, error) {
+	value, err := c.stringValue(key, regexpFormat)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, &InvalidValue{key, regexpFormat}
+	}
+	return re, nil
+}
+
+// GetFileMode parses and returns file permissions from the config file. The
+// value must be a quoted octal number, for example: "0644" or "1777".
+//
+// Unquoted numbers are rejected: YAML only reads them as octal when they start
+// with 0, so 400 would be the decimal number 400, which is 0620 in octal, and
+// both are plain integers once the file is parsed.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetFileMode(key string) (os.FileMode, error) {
+	return DefaultConfig.GetFileMode(key)
+}
+
+func (c *Configuration) GetFileMode(key string) (os.FileMode, error) {
+	value, err := c.value(key)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case os.FileMode:
+		return v, nil
+	case string:
+		if mode, err := strconv.ParseUint(v, 8, 12); err == nil {
+			return fileMode(mode), nil
+		}
+	}
+	return 0, &InvalidValue{key, fileModeFormat}
+}
+
+// fileMode converts unix permission bits, including the setuid, setgid and
+// sticky bits, to an os.FileMode.
+func fileMode(mode uint64) os.FileMode {
+	result := os.FileMode(mode) & os.ModePerm
+	if mode&04000 != 0 {
+		result |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		result |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		result |= os.ModeSticky
+	}
+	return result
+}
+
+// GetTime parses and returns a time, in RFC3339 format, from the config file,
+// for example: 2006-01-02T15:04:05Z07:00.
+//
+// It returns ErrNullValue if the key is set to null.
+func GetTime(key string) (time.Time, error) {
+	return DefaultConfig.GetTime(key)
+}
+
+func (c *Configuration) GetTime(key string) (time.Time, error) {
+	value, err := c.value(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, &InvalidValue{key, timeFormat}
+}
+
+// stringValue works like GetString, but reports values that are not strings
+// as an InvalidValue of the given kind.
+func (c *Configuration) stringValue(key, kind string) (string, error) {
+	value, err := c.GetString(key)
+	if _, ok := err.(*InvalidValue); ok {
+		return "", &InvalidValue{key, kind}
+	}
+	return value, err
+}
@@ -0,0 +1,148 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestGetURL(c *check.C) {
+	Set("api", "https://tsuru.example.com:8080/api?x=1")
+	Set("relative", "/api")
+	Set("port", 8080)
+	u, err := GetURL("api")
+	c.Assert(err, check.IsNil)
+	c.Assert(u.Scheme, check.Equals, "https")
+	c.Assert(u.Host, check.Equals, "tsuru.example.com:8080")
+	c.Assert(u.Path, check.Equals, "/api")
+	_, err = GetURL("relative")
+	c.Assert(err, check.ErrorMatches, `value for the key "relative" is not a URL \(e.g. https://example.com/path\)`)
+	_, err = GetURL("port")
+	c.Assert(err, check.FitsTypeOf, &InvalidValue{})
+	_, err = GetURL("unknown")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "unknown"})
+}
+
+func (s *S) TestGetURLExpandVars(c *check.C) {
+	os.Setenv("TSURU_HOST", "tsuru.example.com")
+	defer os.Unsetenv("TSURU_HOST")
+	Set("api", "https://$TSURU_HOST/api")
+	u, err := GetURL("api")
+	c.Assert(err, check.IsNil)
+	c.Assert(u.Host, check.Equals, "tsuru.example.com")
+}
+
+func (s *S) TestGetIP(c *check.C) {
+	Set("v4", "192.168.0.1")
+	Set("v6", "::1")
+	Set("invalid", "192.168.0.300")
+	Set("bool", true)
+	ip, err := GetIP("v4")
+	c.Assert(err, check.IsNil)
+	c.Assert(ip.Equal(net.IPv4(192, 168, 0, 1)), check.Equals, true)
+	ip, err = GetIP("v6")
+	c.Assert(err, check.IsNil)
+	c.Assert(ip.Equal(net.IPv6loopback), check.Equals, true)
+	_, err = GetIP("invalid")
+	c.Assert(err, check.ErrorMatches, `value for the key "invalid" is not a valid IP address \(e.g. 192.168.0.1 or ::1\)`)
+	_, err = GetIP("bool")
+	c.Assert(err, check.ErrorMatches, `value for the key "bool" is not a valid IP address .*`)
+}
+
+func (s *S) TestGetIPNet(c *check.C) {
+	Set("network", "10.0.0.0/8")
+	Set("invalid", "10.0.0.0")
+	ipNet, err := GetIPNet("network")
+	c.Assert(err, check.IsNil)
+	c.Assert(ipNet.String(), check.Equals, "10.0.0.0/8")
+	c.Assert(ipNet.Contains(net.IPv4(10, 1, 2, 3)), check.Equals, true)
+	_, err = GetIPNet("invalid")
+	c.Assert(err, check.ErrorMatches, `value for the key "invalid" is not a CIDR \(e.g. 10.0.0.0/8\)`)
+}
+
+func (s *S) TestGetHostPort(c *check.C) {
+	Set("listen", "localhost:8080")
+	Set("ipv6", "[::1]:8443")
+	Set("noport", "localhost")
+	Set("badport", "localhost:http")
+	Set("bigport", "localhost:70000")
+	host, port, err := GetHostPort("listen")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "localhost")
+	c.Assert(port, check.Equals, 8080)
+	host, port, err = GetHostPort("ipv6")
+	c.Assert(err, check.IsNil)
+	c.Assert(host, check.Equals, "::1")
+	c.Assert(port, check.Equals, 8443)
+	for _, key := range []string{"noport", "badport", "bigport"} {
+		_, _, err = GetHostPort(key)
+		c.Check(err, check.DeepEquals, &InvalidValue{key, hostPortFormat})
+	}
+	c.Assert(err, check.ErrorMatches, `value for the key "bigport" is not a host:port \(e.g. localhost:8080\)`)
+}
+
+func (s *S) TestGetRegexp(c *check.C) {
+	Set("pattern", `^app-\d+$`)
+	Set("invalid", `^app-(`)
+	re, err := GetRegexp("pattern")
+	c.Assert(err, check.IsNil)
+	c.Assert(re.MatchString("app-10"), check.Equals, true)
+	_, err = GetRegexp("invalid")
+	c.Assert(err, check.ErrorMatches, `value for the key "invalid" is not a regular expression \(RE2 syntax\)`)
+}
+
+func (s *S) TestGetFileMode(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+octal: 0644
+string: "0755"
+sticky: "1777"
+unquoted: 755
+small: 400
+invalid: "0999"
+negative: "-1"
+`))
+	c.Assert(err, check.IsNil)
+	_, err = GetFileMode("octal")
+	c.Assert(err, check.ErrorMatches, `value for the key "octal" is not a quoted file mode \(e.g. "0644"\)`)
+	mode, err := GetFileMode("string")
+	c.Assert(err, check.IsNil)
+	c.Assert(mode, check.Equals, os.FileMode(0755))
+	mode, err = GetFileMode("sticky")
+	c.Assert(err, check.IsNil)
+	c.Assert(mode, check.Equals, os.ModeSticky|0777)
+	_, err = GetFileMode("unquoted")
+	c.Assert(err, check.FitsTypeOf, &InvalidValue{})
+	_, err = GetFileMode("small")
+	c.Assert(err, check.DeepEquals, &InvalidValue{"small", fileModeFormat})
+	_, err = GetFileMode("invalid")
+	c.Assert(err, check.ErrorMatches, `value for the key "invalid" is not a quoted file mode \(e.g. "0644"\)`)
+	_, err = GetFileMode("negative")
+	c.Assert(err, check.FitsTypeOf, &InvalidValue{})
+	Set("typed", os.FileMode(0600))
+	mode, err = GetFileMode("typed")
+	c.Assert(err, check.IsNil)
+	c.Assert(mode, check.Equals, os.FileMode(0600))
+}
+
+func (s *S) TestGetTime(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+deadline: 2020-01-02T15:04:05Z
+offset: "2020-01-02T15:04:05-03:00"
+date: 2020-01-02
+`))
+	c.Assert(err, check.IsNil)
+	t, err := GetTime("deadline")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Equal(time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC)), check.Equals, true)
+	t, err = GetTime("offset")
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Equal(time.Date(2020, 1, 2, 18, 4, 5, 0, time.UTC)), check.Equals, true)
+	_, err = GetTime("date")
+	c.Assert(err, check.ErrorMatches, `value for the key "date" is not a time \(RFC3339, e.g. 2006-01-02T15:04:05Z\)`)
+}
@@ -0,0 +1,258 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errInvalidUnit      = errors.New("invalid unit")
+	errDurationOverflow = errors.New("duration out of range")
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// byteUnits maps the lower case suffixes accepted by GetByteSize to their
+// multipliers. IEC suffixes (KiB, MiB, ...) and single letters are powers of
+// 1024, while SI suffixes (KB, MB, ...) are powers of 1000.
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kib": 1 << 10,
+	"kb":  1e3,
+	"m":   1 << 20,
+	"mib": 1 << 20,
+	"mb":  1e6,
+	"g":   1 << 30,
+	"gib": 1 << 30,
+	"gb":  1e9,
+	"t":   1 << 40,
+	"tib": 1 << 40,
+	"tb":  1e12,
+	"p":   1 << 50,
+	"pib": 1 << 50,
+	"pb":  1e15,
+}
+
+// GetByteSize parses and returns an amount of bytes from the config file. It
+// may be an integer or a number followed by a unit, with or without spaces.
+// Units are case insensitive: KiB, MiB, GiB, TiB and PiB, as well as the
+// single letters K, M, G, T and P, are powers of 1024, while KB, MB, GB, TB
+// and PB are powers of 1000.
+//
+// Here are some examples of valid sizes:
+//
+//  - 104857600
+//  - 100MiB (104857600 bytes)
+//  - 1.5GB (1500000000 bytes)
+//  - 512k (524288 bytes)
+//
+// It returns ErrNullValue if the key is set to null.
+func GetByteSize(key string) (uint64, error) {
+	return DefaultConfig.GetByteSize(key)
+}
+
+func (c *Configuration) GetByteSize(key string) (uint64, error) {
+	value, err := c.value(key)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case int:
+		if v >= 0 {
+			return uint64(v), nil
+		}
+	case uint64:
+		return v, nil
+	case float64:
+		if v >= 0 && v == math.Trunc(v) && v < math.MaxUint64 {
+			return uint64(v), nil
+		}
+	case string:
+		if size, err := parseByteSize(v); err == nil {
+			return size, nil
+		}
+	}
+	return 0, &InvalidValue{key, "byte size (e.g. 10MiB or 1.5GB)"}
+}
+
+func parseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	number, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, err
+	}
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, errInvalidUnit
+	}
+	size := number * unit
+	if size != math.Trunc(size) || size >= math.MaxUint64 {
+		return 0, errInvalidUnit
+	}
+	return uint64(size), nil
+}
+
+// parseDuration parses durations in the formats accepted by GetDuration.
+func parseDuration(s string) (time.Duration, error) {
+	if duration, err := time.ParseDuration(s); err == nil {
+		return duration, nil
+	}
+	if number, err := strconv.ParseFloat(s, 64); err == nil {
+		return scaleDuration(number, 1)
+	}
+	if strings.HasPrefix(strings.TrimLeft(s, "+-"), "P") {
+		return parseISODuration(s)
+	}
+	return parseExtendedDuration(s)
+}
+
+// parseExtendedDuration parses durations in the Go syntax extended with the
+// units "d" (24 hours) and "w" (7 days), for example: 1w2d12h.
+func parseExtendedDuration(s string) (time.Duration, error) {
+	sign, s := durationSign(s)
+	if s == "" {
+		return 0, errInvalidUnit
+	}
+	var total time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i <= 0 {
+			return 0, errInvalidUnit
+		}
+		j := strings.IndexFunc(s[i:], func(r rune) bool {
+			return (r >= '0' && r <= '9') || r == '.'
+		})
+		if j < 0 {
+			j = len(s) - i
+		}
+		number, unit := s[:i], s[i:i+j]
+		s = s[i+j:]
+		var part time.Duration
+		switch unit {
+		case "d", "w":
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, err
+			}
+			scale := day
+			if unit == "w" {
+				scale = week
+			}
+			if part, err = scaleDuration(n, scale); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if part, err = time.ParseDuration(number + unit); err != nil {
+				return 0, err
+			}
+		}
+		var err error
+		if total, err = addDuration(total, part); err != nil {
+			return 0, err
+		}
+	}
+	return sign * total, nil
+}
+
+// parseISODuration parses ISO-8601 durations, for example: PT30S or P1DT12H.
+// Years and months are not accepted, as their length varies.
+func parseISODuration(s string) (time.Duration, error) {
+	sign, s := durationSign(s)
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, errInvalidUnit
+	}
+	s = s[1:]
+	var total time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			if inTime || len(s) == 1 {
+				return 0, errInvalidUnit
+			}
+			inTime = true
+			s = s[1:]
+			continue
+		}
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != ','
+		})
+		if i <= 0 {
+			return 0, errInvalidUnit
+		}
+		n, err := strconv.ParseFloat(strings.Replace(s[:i], ",", ".", 1), 64)
+		if err != nil {
+			return 0, err
+		}
+		var unit time.Duration
+		switch d := s[i]; {
+		case !inTime && d == 'W':
+			unit = week
+		case !inTime && d == 'D':
+			unit = day
+		case inTime && d == 'H':
+			unit = time.Hour
+		case inTime && d == 'M':
+			unit = time.Minute
+		case inTime && d == 'S':
+			unit = time.Second
+		default:
+			return 0, errInvalidUnit
+		}
+		part, err := scaleDuration(n, unit)
+		if err != nil {
+			return 0, err
+		}
+		if total, err = addDuration(total, part); err != nil {
+			return 0, err
+		}
+		s = s[i+1:]
+	}
+	return sign * total, nil
+}
+
+// scaleDuration returns n times unit, failing when the result doesn't fit in
+// a time.Duration, like time.ParseDuration does.
+func scaleDuration(n float64, unit time.Duration) (time.Duration, error) {
+	d := n * float64(unit)
+	if d >= math.MaxInt64 || d < math.MinInt64 || math.IsNaN(d) {
+		return 0, errDurationOverflow
+	}
+	return time.Duration(d), nil
+}
+
+// addDuration returns the sum of two non-negative durations, failing when it
+// doesn't fit in a time.Duration.
+func addDuration(a, b time.Duration) (time.Duration, error) {
+	if a > math.MaxInt64-b {
+		return 0, errDurationOverflow
+	}
+	return a + b, nil
+}
+
+func durationSign(s string) (time.Duration, string) {
+	if strings.HasPrefix(s, "-") {
+		return -1, s[1:]
+	}
+	return 1, strings.TrimPrefix(s, "+")
+}
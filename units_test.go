@@ -0,0 +1,131 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestGetByteSize(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+raw: 104857600
+float: 1e6
+string: "2048"
+mib: 10MiB
+gb: 1.5GB
+k: 512k
+spaced: 2 KiB
+lower: 1mb
+bytes: 100B
+`))
+	c.Assert(err, check.IsNil)
+	var tests = []struct {
+		key      string
+		expected uint64
+	}{
+		{"raw", 104857600},
+		{"float", 1000000},
+		{"string", 2048},
+		{"mib", 10 << 20},
+		{"gb", 1500000000},
+		{"k", 512 << 10},
+		{"spaced", 2048},
+		{"lower", 1000000},
+		{"bytes", 100},
+	}
+	for _, t := range tests {
+		value, err := GetByteSize(t.key)
+		c.Check(err, check.IsNil, check.Commentf("key: %s", t.key))
+		c.Check(value, check.Equals, t.expected, check.Commentf("key: %s", t.key))
+	}
+}
+
+func (s *S) TestGetByteSizeInvalid(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+negative: -1
+unit: 10XB
+fraction: 1.5B
+text: big
+bool: true
+`))
+	c.Assert(err, check.IsNil)
+	for _, key := range []string{"negative", "unit", "fraction", "text", "bool"} {
+		_, err := GetByteSize(key)
+		c.Check(err, check.DeepEquals, &InvalidValue{key, "byte size (e.g. 10MiB or 1.5GB)"})
+	}
+	_, err = GetByteSize("unknown")
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "unknown"})
+}
+
+func (s *S) TestGetDurationExtendedUnits(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+days: 2d
+weeks: 1w
+mixed: 1w2d12h30m
+fraction: 1.5d
+negative: -1d
+iso-seconds: PT30S
+iso-mixed: P1DT12H
+iso-weeks: P2W
+iso-fraction: PT1.5M
+iso-negative: -PT1H
+`))
+	c.Assert(err, check.IsNil)
+	var tests = []struct {
+		key      string
+		expected time.Duration
+	}{
+		{"days", 48 * time.Hour},
+		{"weeks", 7 * 24 * time.Hour},
+		{"mixed", 9*24*time.Hour + 12*time.Hour + 30*time.Minute},
+		{"fraction", 36 * time.Hour},
+		{"negative", -24 * time.Hour},
+		{"iso-seconds", 30 * time.Second},
+		{"iso-mixed", 36 * time.Hour},
+		{"iso-weeks", 14 * 24 * time.Hour},
+		{"iso-fraction", 90 * time.Second},
+		{"iso-negative", -time.Hour},
+	}
+	for _, t := range tests {
+		value, err := GetDuration(t.key)
+		c.Check(err, check.IsNil, check.Commentf("key: %s", t.key))
+		c.Check(value, check.Equals, t.expected, check.Commentf("key: %s", t.key))
+	}
+}
+
+func (s *S) TestParseDurationOverflow(c *check.C) {
+	for _, value := range []string{"20000w", "P20000W", "-20000w", "106752d1h", "PT2562048H"} {
+		_, err := parseDuration(value)
+		c.Check(err, check.Equals, errDurationOverflow, check.Commentf("value: %s", value))
+	}
+	d, err := parseDuration("106751d")
+	c.Assert(err, check.IsNil)
+	c.Assert(d, check.Equals, 106751*24*time.Hour)
+}
+
+func (s *S) TestGetDurationExtendedUnitsInvalid(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+unit: 1y
+missing-number: d
+iso-years: P1Y
+iso-months: P1M
+iso-empty: PT
+iso-time-unit: P1H
+trailing: 1d2
+overflow-weeks: 20000w
+overflow-iso: P20000W
+overflow-sum: 10000w10000w
+overflow-iso-sum: P15000W2000D
+overflow-number: "1e30"
+`))
+	c.Assert(err, check.IsNil)
+	for _, key := range []string{"unit", "missing-number", "iso-years", "iso-months", "iso-empty", "iso-time-unit", "trailing",
+		"overflow-weeks", "overflow-iso", "overflow-sum", "overflow-iso-sum", "overflow-number"} {
+		_, err := GetDuration(key)
+		c.Check(err, check.DeepEquals, &InvalidValue{key, "duration"}, check.Commentf("key: %s", key))
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UnknownKeys returns the keys defined in the configuration that are not in
+// the given list of known keys, sorted by name.
+//
+// Known keys use the format of MarkSensitive patterns: parts may contain the
+// wildcards supported by path.Match, and keys below a known key are known too,
+// so "pools" accepts any key inside the "pools" section. A key with the
+// "_file" suffix is known when the key without the suffix is. Sections with
+// no known keys are reported as a whole.
+func UnknownKeys(known []string) []string {
+	return DefaultConfig.UnknownKeys(known)
+}
+
+func (c *Configuration) UnknownKeys(known []string) []string {
+	c.RLock()
+	defer c.RUnlock()
+	patterns := make([][]string, len(known))
+	for i, k := range known {
+		patterns[i] = strings.Split(k, ":")
+	}
+	var unknown []string
+	var walk func(m map[interface{}]interface{}, prefix string)
+	walk = func(m map[interface{}]interface{}, prefix string) {
+		for k, v := range m {
+			key := joinKey(prefix, k)
+			parts := strings.Split(strings.TrimSuffix(key, fileKeySuffix), ":")
+			if knownKey(patterns, parts) {
+				continue
+			}
+			if inner, ok := v.(map[interface{}]interface{}); ok && knownSection(patterns, parts) {
+				walk(inner, key)
+				continue
+			}
+			unknown = append(unknown, key)
+		}
+	}
+	walk(c.data, "")
+	sort.Strings(unknown)
+	return unknown
+}
+
+// knownKey reports whether any of the patterns matches the key, or one of its
+// parents.
+func knownKey(patterns [][]string, parts []string) bool {
+	for _, pattern := range patterns {
+		if len(pattern) <= len(parts) && matchParts(pattern, parts) {
+			return true
+		}
+	}
+	return false
+}
+
+// knownSection reports whether any of the patterns matches a key inside the
+// section.
+func knownSection(patterns [][]string, parts []string) bool {
+	for _, pattern := range patterns {
+		if len(pattern) > len(parts) && matchParts(pattern[:len(parts)], parts) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchParts(pattern, parts []string) bool {
+	for i, p := range pattern {
+		if ok, err := path.Match(p, parts[i]); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckUnknownKeys returns a Checker that emits a warning (see NewWarning)
+// listing the keys reported by UnknownKeys. When an unknown key looks like a
+// misspelling of a known key, the warning suggests it:
+//
+//   unknown key "databse:host" (did you mean "database:host"?)
+//
+// Known keys may come from a registry, using Registry.Names, or from a
+// struct, using StructKeys.
+func CheckUnknownKeys(known []string) Checker {
+	return DefaultConfig.CheckUnknownKeys(known)
+}
+
+func (c *Configuration) CheckUnknownKeys(known []string) Checker {
+	return func() error {
+		unknown := c.UnknownKeys(known)
+		if len(unknown) == 0 {
+			return nil
+		}
+		keyed := make([]ValidationError, len(unknown))
+		for i, key := range unknown {
+			keyed[i] = ValidationError{Key: key, Message: fmt.Sprintf("unknown key %q", key)}
+			if suggestion, ok := suggestKey(key, known); ok {
+				keyed[i].Message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+		}
+		return keyedWarning(keyed)
+	}
+}
+
+// suggestKey returns the known key closest to the given unknown key, if the
+// distance between them is small enough to be a typo. Known keys deeper than
+// the unknown key are compared by their parent at the same depth.
+func suggestKey(key string, known []string) (string, bool) {
+	depth := strings.Count(key, ":") + 1
+	best, bestDistance := "", -1
+	for _, k := range known {
+		if strings.ContainsAny(k, "*?[") {
+			continue
+		}
+		parts := strings.Split(k, ":")
+		if len(parts) < depth {
+			continue
+		}
+		candidate := strings.Join(parts[:depth], ":")
+		d := editDistance(key, candidate)
+		if bestDistance < 0 || d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	if bestDistance < 0 || bestDistance > maxTypoDistance(key) {
+		return "", false
+	}
+	return best, true
+}
+
+// maxTypoDistance returns the largest edit distance considered a typo for the
+// given key.
+func maxTypoDistance(key string) int {
+	if d := len(key) / 4; d < 3 {
+		return d
+	}
+	return 3
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// StructKeys returns the keys described by the given struct, or pointer to
+// struct, following the rules of yaml.Unmarshal: fields are named by their
+// yaml tag, or by their lowercased name, fields tagged with "-" are skipped
+// and fields tagged with ",inline" are merged into the parent. Nested structs
+// describe sections, any other field is a key.
+func StructKeys(v interface{}) []string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	keys := structKeys(t, "")
+	sort.Strings(keys)
+	return keys
+}
+
+func structKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, inline, ok := yamlField(field)
+		if !ok {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if inline {
+			if ft.Kind() == reflect.Struct {
+				keys = append(keys, structKeys(ft, prefix)...)
+			} else {
+				keys = append(keys, joinKey(prefix, "*"))
+			}
+			continue
+		}
+		key := joinKey(prefix, name)
+		if ft.Kind() == reflect.Struct && ft != timeType {
+			if sub := structKeys(ft, key); len(sub) > 0 {
+				keys = append(keys, sub...)
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// yamlField returns the name of the key decoded into the given field by
+// yaml.Unmarshal, and whether the field is inlined. It returns false for
+// fields that are not decoded.
+func yamlField(field reflect.StructField) (name string, inline bool, ok bool) {
+	if field.PkgPath != "" && !field.Anonymous {
+		return "", false, false
+	}
+	tag := field.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, opts := tag, ""
+	if i := strings.Index(tag, ","); i >= 0 {
+		name, opts = tag[:i], tag[i+1:]
+	}
+	if strings.Contains(opts, "inline") {
+		return "", true, true
+	}
+	if field.PkgPath != "" {
+		return "", false, false
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, false, true
+}
@@ -0,0 +1,138 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"time"
+
+	"gopkg.in/check.v1"
+)
+
+const typoConfig = `
+databse:
+  host: localhost
+database:
+  url: 127.0.0.1:27017
+  nmae: tsuru
+  password_file: /run/secrets/db
+pools:
+  default:
+    public: true
+debug: true
+`
+
+func (s *S) TestUnknownKeys(c *check.C) {
+	err := ReadConfigBytes([]byte(typoConfig))
+	c.Assert(err, check.IsNil)
+	known := []string{"database:url", "database:name", "database:password", "pools", "debug"}
+	c.Assert(UnknownKeys(known), check.DeepEquals, []string{"database:nmae", "databse"})
+}
+
+func (s *S) TestUnknownKeysPatterns(c *check.C) {
+	err := ReadConfigBytes([]byte(typoConfig))
+	c.Assert(err, check.IsNil)
+	known := []string{"database:*", "pools:*:public", "debug", "databse:host"}
+	c.Assert(UnknownKeys(known), check.HasLen, 0)
+	known = []string{"database:*", "pools:*:private", "debug", "databse:host"}
+	c.Assert(UnknownKeys(known), check.DeepEquals, []string{"pools:default:public"})
+}
+
+func (s *S) TestUnknownKeysEmptyConfig(c *check.C) {
+	var conf Configuration
+	c.Assert(conf.UnknownKeys([]string{"debug"}), check.HasLen, 0)
+}
+
+func (s *S) TestCheckUnknownKeys(c *check.C) {
+	err := ReadConfigBytes([]byte(typoConfig))
+	c.Assert(err, check.IsNil)
+	known := []string{"database:url", "database:name", "database:password", "database:host", "pools", "debug"}
+	checker := CheckUnknownKeys(known)
+	err = checker()
+	c.Assert(err, check.FitsTypeOf, &warningErr{})
+	c.Assert(err, check.ErrorMatches, `unknown key "database:nmae" \(did you mean "database:name"\?\); `+
+		`unknown key "databse" \(did you mean "database"\?\)`)
+	var buf bytes.Buffer
+	err = CheckWithWarnings([]Checker{checker}, &buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Matches, `WARNING: unknown key "database:nmae".*\n`)
+}
+
+func (s *S) TestCheckUnknownKeysWithoutSuggestion(c *check.C) {
+	var conf Configuration
+	conf.Set("queue", "redis")
+	err := conf.CheckUnknownKeys([]string{"debug"})()
+	c.Assert(err, check.ErrorMatches, `unknown key "queue"`)
+	conf.Unset("queue")
+	c.Assert(conf.CheckUnknownKeys([]string{"debug"})(), check.IsNil)
+}
+
+func (s *S) TestCheckUnknownKeysRegistry(c *check.C) {
+	var r Registry
+	r.Register(Key{Name: "database:url", Type: String})
+	r.Register(Key{Name: "debug", Type: Bool})
+	var conf Configuration
+	conf.Set("database:ulr", "127.0.0.1:27017")
+	conf.Set("debug", true)
+	err := conf.CheckUnknownKeys(r.Names())()
+	c.Assert(err, check.ErrorMatches, `unknown key "database:ulr" \(did you mean "database:url"\?\)`)
+}
+
+func (s *S) TestEditDistance(c *check.C) {
+	c.Assert(editDistance("", ""), check.Equals, 0)
+	c.Assert(editDistance("database", "databse"), check.Equals, 1)
+	c.Assert(editDistance("kitten", "sitting"), check.Equals, 3)
+	c.Assert(editDistance("", "abc"), check.Equals, 3)
+}
+
+func (s *S) TestSuggestKey(c *check.C) {
+	known := []string{"database:url", "debug", "pools:*:public"}
+	key, ok := suggestKey("debgu", known)
+	c.Assert(ok, check.Equals, false)
+	key, ok = suggestKey("debu", known)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(key, check.Equals, "debug")
+	_, ok = suggestKey("provisioner", known)
+	c.Assert(ok, check.Equals, false)
+}
+
+type structKeysConfig struct {
+	Database databaseConfig `yaml:"database"`
+	Debug    bool
+	Started  time.Time              `yaml:"started"`
+	Pools    map[string]interface{} `yaml:"pools"`
+	Ignored  string                 `yaml:"-"`
+	Extra    struct {
+		Queue string `yaml:"queue"`
+	} `yaml:",inline"`
+	private string
+}
+
+func (s *S) TestStructKeys(c *check.C) {
+	keys := StructKeys(&structKeysConfig{})
+	c.Assert(keys, check.DeepEquals, []string{
+		"database:host",
+		"database:password",
+		"database:port",
+		"database:replicas",
+		"database:timeout",
+		"debug",
+		"pools",
+		"queue",
+		"started",
+	})
+	c.Assert(StructKeys(42), check.IsNil)
+	c.Assert(StructKeys(nil), check.IsNil)
+}
+
+func (s *S) TestUnknownKeysStruct(c *check.C) {
+	err := ReadConfigBytes([]byte(typoConfig))
+	c.Assert(err, check.IsNil)
+	c.Assert(UnknownKeys(StructKeys(structKeysConfig{})), check.DeepEquals, []string{
+		"database:nmae",
+		"database:url",
+		"databse",
+	})
+}
@@ -0,0 +1,270 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError describes a value that breaks a validation rule.
+type ValidationError struct {
+	// Key is the key holding the invalid value, in the format used by Get.
+	// Items of lists are identified by their index, for example
+	// "pools[2]:name".
+	Key string
+
+	// Message describes the broken rule.
+	Message string
+}
+
+func (e ValidationError) String() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// ErrValidation is returned by Validate when values break the rules declared
+// in the struct tags. It lists every broken rule, including the ones
+// declared as warnings.
+type ErrValidation struct {
+	Errors   []ValidationError
+	Warnings []ValidationError
+}
+
+func (e *ErrValidation) Error() string {
+	return fmt.Sprintf("invalid configuration: %s", joinValidationErrors(e.Errors))
+}
+
+func joinValidationErrors(errs []ValidationError) string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate decodes the section under the given key into v, like Unmarshal
+// does, and validates the values according to the rules declared in the
+// "validate" tag of the fields, for example:
+//
+//   type databaseConfig struct {
+//       URL  string `yaml:"url" validate:"required,url"`
+//       Port int    `yaml:"port" validate:"min=1,max=65535"`
+//       Mode string `yaml:"mode" validate:"oneof=primary secondary"`
+//   }
+//
+// The following rules are supported:
+//
+//  - required: the key must be defined, and not null
+//  - min=N and max=N: limits for numbers, or for the length of strings, lists
+//    and maps
+//  - oneof=a b c: the value must be one of the space separated values
+//  - url: the value must be an absolute URL
+//
+// Rules other than required are only checked for keys that are defined.
+// Nested structs, and structs in lists and maps, are validated too.
+//
+// Rules declared in the "warn" tag, with the same syntax, produce warnings
+// instead of errors. When only warnings are found, Validate returns them as a
+// warning (see NewWarning), so CheckWithWarnings reports them without failing.
+// Otherwise it returns an *ErrValidation listing every error, keyed by its
+// full key, along with the warnings.
+func Validate(key string, v interface{}) error {
+	return DefaultConfig.Validate(key, v)
+}
+
+func (c *Configuration) Validate(key string, v interface{}) error {
+	if err := c.Unmarshal(key, v); err != nil {
+		return err
+	}
+	val := &validation{c: c}
+	val.value(reflect.ValueOf(v), key, true)
+	sortValidationErrors(val.errors)
+	sortValidationErrors(val.warnings)
+	if len(val.errors) > 0 {
+		return &ErrValidation{Errors: val.errors, Warnings: val.warnings}
+	}
+	if len(val.warnings) > 0 {
+		return &warningErr{msg: joinValidationErrors(val.warnings), keyed: val.warnings}
+	}
+	return nil
+}
+
+// CheckStruct returns a Checker that validates the section under the given
+// key, see Validate. The value is used as a prototype: each check decodes the
+// configuration into a new value of the same type.
+func CheckStruct(key string, v interface{}) Checker {
+	return DefaultConfig.CheckStruct(key, v)
+}
+
+func (c *Configuration) CheckStruct(key string, v interface{}) Checker {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return func() error {
+		return c.Validate(key, reflect.New(t).Interface())
+	}
+}
+
+func sortValidationErrors(errs []ValidationError) {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+}
+
+type validation struct {
+	c        *Configuration
+	errors   []ValidationError
+	warnings []ValidationError
+}
+
+// value validates the fields of structs found in v. Keys inside lists can't
+// be read with Get, so addressable is false for them.
+func (val *validation) value(v reflect.Value, key string, addressable bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, inline, ok := yamlField(field)
+			if !ok {
+				continue
+			}
+			fieldKey := key
+			if !inline {
+				fieldKey = joinKey(key, name)
+				val.field(v.Field(i), field, fieldKey, addressable)
+			}
+			val.value(v.Field(i), fieldKey, addressable)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			val.value(v.Index(i), itemKey(key, i), false)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			val.value(iter.Value(), joinKey(key, iter.Key().Interface()), addressable)
+		}
+	}
+}
+
+// field checks the rules declared in the tags of the given field.
+func (val *validation) field(v reflect.Value, field reflect.StructField, key string, addressable bool) {
+	defined := !v.IsZero()
+	if addressable {
+		_, err := val.c.value(key)
+		defined = !isMissing(err)
+	}
+	if rules, ok := field.Tag.Lookup("validate"); ok {
+		val.errors = append(val.errors, checkRules(v, rules, key, defined)...)
+	}
+	if rules, ok := field.Tag.Lookup("warn"); ok {
+		val.warnings = append(val.warnings, checkRules(v, rules, key, defined)...)
+	}
+}
+
+func checkRules(v reflect.Value, rules, key string, defined bool) []ValidationError {
+	var errs []ValidationError
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+		if name == "required" {
+			if !defined {
+				errs = append(errs, ValidationError{Key: key, Message: "is required"})
+			}
+			continue
+		}
+		if !defined {
+			continue
+		}
+		if msg := checkRule(v, name, arg); msg != "" {
+			errs = append(errs, ValidationError{Key: key, Message: msg})
+		}
+	}
+	return errs
+}
+
+// checkRule returns a message describing the broken rule, or an empty string
+// if the value follows it.
+func checkRule(v reflect.Value, name, arg string) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("invalid rule %q", name+"="+arg)
+		}
+		n, unit, ok := measure(v)
+		if !ok {
+			return fmt.Sprintf("rule %q doesn't apply to %s values", name, v.Kind())
+		}
+		if name == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s%s", arg, unit)
+		}
+		if name == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s%s", arg, unit)
+		}
+	case "oneof":
+		allowed := strings.Fields(arg)
+		value := fmt.Sprint(v.Interface())
+		for _, a := range allowed {
+			if a == value {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of: %s", strings.Join(allowed, ", "))
+	case "url":
+		if v.Kind() != reflect.String {
+			return fmt.Sprintf("rule %q doesn't apply to %s values", name, v.Kind())
+		}
+		if u, err := url.Parse(v.String()); err != nil || u.Scheme == "" {
+			return "must be a " + urlFormat
+		}
+	default:
+		return fmt.Sprintf("unknown rule %q", name)
+	}
+	return ""
+}
+
+// measure returns the number compared by the min and max rules: the value of
+// numbers, or the length of strings, lists and maps, along with its unit.
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	case reflect.String:
+		return float64(len(v.String())), " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", true
+	}
+	return 0, "", false
+}
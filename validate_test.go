@@ -0,0 +1,168 @@
+// Copyright 2015 Globo.com. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+
+	"gopkg.in/check.v1"
+)
+
+type validatedPool struct {
+	Name    string `yaml:"name" validate:"required,min=2"`
+	Default bool   `yaml:"default"`
+}
+
+type validatedConfig struct {
+	Database struct {
+		URL  string `yaml:"url" validate:"required,url"`
+		Port int    `yaml:"port" validate:"min=1,max=65535" warn:"min=1024"`
+		Mode string `yaml:"mode" validate:"oneof=primary secondary"`
+	} `yaml:"database" validate:"required"`
+	Hosts   []string                 `yaml:"hosts" validate:"max=2"`
+	Pools   []validatedPool          `yaml:"pools"`
+	Queues  map[string]validatedPool `yaml:"queues"`
+	Workers *int                     `yaml:"workers" validate:"required"`
+	Debug   bool                     `yaml:"debug" warn:"oneof=false"`
+}
+
+func (s *S) TestValidate(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+database:
+  url: mongodb://localhost:27017
+  port: 27017
+  mode: primary
+hosts: [a, b]
+pools:
+  - name: default
+workers: 0
+`))
+	c.Assert(err, check.IsNil)
+	var conf validatedConfig
+	err = Validate("", &conf)
+	c.Assert(err, check.IsNil)
+	c.Assert(conf.Database.Port, check.Equals, 27017)
+	c.Assert(*conf.Workers, check.Equals, 0)
+}
+
+func (s *S) TestValidateErrors(c *check.C) {
+	err := ReadConfigBytes([]byte(`
+database:
+  url: localhost
+  port: 70000
+  mode: arbiter
+hosts: [a, b, c]
+pools:
+  - name: default
+  - name: x
+  - default: true
+queues:
+  events:
+    name: e
+debug: true
+`))
+	c.Assert(err, check.IsNil)
+	var conf validatedConfig
+	err = Validate("", &conf)
+	c.Assert(err, check.FitsTypeOf, &ErrValidation{})
+	e := err.(*ErrValidation)
+	c.Assert(e.Errors, check.DeepEquals, []ValidationError{
+		{Key: "database:mode", Message: "must be one of: primary, secondary"},
+		{Key: "database:port", Message: "must be at most 65535"},
+		{Key: "database:url", Message: "must be a URL (e.g. https://example.com/path)"},
+		{Key: "hosts", Message: "must be at most 2 items"},
+		{Key: "pools[1]:name", Message: "must be at least 2 characters"},
+		{Key: "pools[2]:name", Message: "is required"},
+		{Key: "queues:events:name", Message: "must be at least 2 characters"},
+		{Key: "workers", Message: "is required"},
+	})
+	c.Assert(e.Warnings, check.DeepEquals, []ValidationError{
+		{Key: "debug", Message: "must be one of: false"},
+	})
+	c.Assert(err, check.ErrorMatches, `invalid configuration: database:mode: must be one of: primary, secondary; database:port: .*; workers: is required`)
+}
+
+func (s *S) TestValidateRequiredSection(c *check.C) {
+	var conf Configuration
+	conf.Set("workers", 2)
+	var v validatedConfig
+	err := conf.Validate("", &v)
+	c.Assert(err, check.FitsTypeOf, &ErrValidation{})
+	c.Assert(err.(*ErrValidation).Errors, check.DeepEquals, []ValidationError{
+		{Key: "database", Message: "is required"},
+		{Key: "database:url", Message: "is required"},
+	})
+}
+
+func (s *S) TestValidateNullIsMissing(c *check.C) {
+	var conf Configuration
+	conf.Set("database:url", nil)
+	var v struct {
+		Database struct {
+			URL string `yaml:"url" validate:"required"`
+		} `yaml:"database"`
+	}
+	err := conf.Validate("", &v)
+	c.Assert(err, check.ErrorMatches, `invalid configuration: database:url: is required`)
+}
+
+func (s *S) TestValidateWarningsOnly(c *check.C) {
+	var conf Configuration
+	conf.Set("database:url", "mongodb://localhost")
+	conf.Set("database:port", 80)
+	conf.Set("workers", 1)
+	var v validatedConfig
+	err := conf.Validate("", &v)
+	c.Assert(err, check.FitsTypeOf, &warningErr{})
+	c.Assert(err, check.ErrorMatches, `database:port: must be at least 1024`)
+}
+
+func (s *S) TestValidateSection(c *check.C) {
+	var conf Configuration
+	conf.Set("database:url", "mongodb://localhost")
+	conf.Set("database:port", 0)
+	var db struct {
+		URL  string `yaml:"url" validate:"required,url"`
+		Port int    `yaml:"port" validate:"min=1"`
+	}
+	err := conf.Validate("database", &db)
+	c.Assert(err, check.ErrorMatches, `invalid configuration: database:port: must be at least 1`)
+	err = conf.Validate("unknown", &db)
+	c.Assert(err, check.DeepEquals, ErrKeyNotFound{Key: "unknown"})
+}
+
+func (s *S) TestValidateInvalidRules(c *check.C) {
+	var conf Configuration
+	conf.Set("a", "x")
+	conf.Set("b", true)
+	conf.Set("c", 1)
+	var v struct {
+		A string `yaml:"a" validate:"max=big"`
+		B bool   `yaml:"b" validate:"min=1"`
+		C int    `yaml:"c" validate:"url,positive"`
+	}
+	err := conf.Validate("", &v)
+	c.Assert(err, check.FitsTypeOf, &ErrValidation{})
+	c.Assert(err.(*ErrValidation).Errors, check.DeepEquals, []ValidationError{
+		{Key: "a", Message: `invalid rule "max=big"`},
+		{Key: "b", Message: `rule "min" doesn't apply to bool values`},
+		{Key: "c", Message: `rule "url" doesn't apply to int values`},
+		{Key: "c", Message: `unknown rule "positive"`},
+	})
+}
+
+func (s *S) TestCheckStruct(c *check.C) {
+	Set("database:url", "mongodb://localhost")
+	Set("database:port", 80)
+	Set("workers", 1)
+	checker := CheckStruct("", &validatedConfig{})
+	var buf bytes.Buffer
+	err := CheckWithWarnings([]Checker{checker}, &buf)
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals, "WARNING: database:port: must be at least 1024\n")
+	Unset("workers")
+	err = CheckWithWarnings([]Checker{checker}, &buf)
+	c.Assert(err, check.ErrorMatches, `invalid configuration: workers: is required`)
+}